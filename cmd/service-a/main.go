@@ -0,0 +1,26 @@
+// Package main runs service A, which validates the CEPs sent by clients and
+// forwards them to service B.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/app"
+)
+
+func main() {
+	cfg, err := app.LoadConfig("service-a", os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	a, err := app.NewServiceA(cfg)
+	if err != nil {
+		log.Fatal("failed to configure service A:", err)
+	}
+
+	if err := app.Run(cfg, "service-a", os.Args[1:], a); err != nil {
+		log.Fatal(err)
+	}
+}
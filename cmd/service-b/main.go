@@ -0,0 +1,37 @@
+// Package main runs service B, which looks up the city of a CEP and its
+// weather.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/app"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import-ceps" {
+		cfg, err := app.LoadConfig("import-ceps", nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := app.ImportCEPs(cfg, os.Args[2:]); err != nil {
+			log.Fatal("failed to import CEPs:", err)
+		}
+		return
+	}
+
+	cfg, err := app.LoadConfig("service-b", os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	b, err := app.NewServiceB(cfg)
+	if err != nil {
+		log.Fatal("failed to configure service B:", err)
+	}
+
+	if err := app.Run(cfg, "service-b", os.Args[1:], b); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,28 @@
+package domain
+
+import "strings"
+
+// Address is the location a postal code resolves to.
+type Address struct {
+	PostalCode   string `json:"cep"`
+	Street       string `json:"street,omitempty"`
+	Neighborhood string `json:"neighborhood,omitempty"`
+	City         string `json:"city"`
+	UF           string `json:"uf,omitempty"`
+	State        string `json:"state,omitempty"`
+	Region       string `json:"region,omitempty"`
+	IBGE         string `json:"ibge,omitempty"`
+	DDD          string `json:"ddd,omitempty"`
+	Source       string `json:"source,omitempty"`
+}
+
+// NormalizePostalCode strips every non-digit character from postalCode, so
+// "01001-000" and "01001000" are treated as the same CEP.
+func NormalizePostalCode(postalCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, postalCode)
+}
@@ -0,0 +1,29 @@
+package domain
+
+// Forecast is the daily and hourly weather forecast for a location.
+type Forecast struct {
+	Days   []ForecastDay `json:"days"`
+	Source string        `json:"source,omitempty"`
+}
+
+// ForecastDay summarizes the forecast for a single day. Date is formatted as
+// YYYY-MM-DD in the location's local time.
+type ForecastDay struct {
+	Date   string         `json:"date"`
+	MinC   float64        `json:"min_C"`
+	MaxC   float64        `json:"max_C"`
+	AvgC   float64        `json:"avg_C"`
+	Hourly []ForecastHour `json:"hourly"`
+}
+
+// ForecastHour is a single forecast step within a day. Time is formatted as
+// HH:MM in the location's local time.
+type ForecastHour struct {
+	Time         string  `json:"time"`
+	TempC        float64 `json:"temp_C"`
+	FeelsLikeC   float64 `json:"feels_like_C"`
+	Humidity     float64 `json:"humidity"`
+	ChanceOfRain float64 `json:"chance_of_rain"`
+	PrecipMM     float64 `json:"precip_mm"`
+	Description  string  `json:"description,omitempty"`
+}
@@ -4,6 +4,8 @@ package domain
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 )
 
 var (
@@ -11,14 +13,83 @@ var (
 	ErrInvalidZipCode = errors.New("invalid zipcode")
 	// ErrPostalCodeNotFound TODO
 	ErrPostalCodeNotFound = errors.New("can not find zipcode")
+	// ErrLocationNotFound is returned by weather providers that know no
+	// place matching the location.
+	ErrLocationNotFound = errors.New("location not found")
 )
 
+// ErrCircuitOpen is returned without calling a provider whose circuit breaker
+// is open. RetryAfter is how long until the provider is tried again.
+type ErrCircuitOpen struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+// Error implements [error].
+func (e ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("circuit %s is open, retry after %s", e.Name, e.RetryAfter.Round(time.Second))
+}
+
+// ErrRateLimited is returned without calling a provider whose rate limit or
+// daily quota has been reached. RetryAfter is how long until it may be called
+// again.
+type ErrRateLimited struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+// Error implements [error].
+func (e ErrRateLimited) Error() string {
+	return fmt.Sprintf("rate limit of %s reached, retry after %s", e.Name, e.RetryAfter.Round(time.Second))
+}
+
+// ErrBulkheadFull is returned without calling a provider that already has
+// as many calls in flight as its bulkhead allows, none of which finished
+// while the call was queued.
+type ErrBulkheadFull struct {
+	Name string
+}
+
+// Error implements [error].
+func (e ErrBulkheadFull) Error() string {
+	return fmt.Sprintf("bulkhead of %s is full", e.Name)
+}
+
+// ErrUntrustedCertificate is returned when the certificate of a provider fails
+// verification, against the trusted CAs or the pins of the provider. It is
+// not worth retrying, as the certificate will not change in between.
+type ErrUntrustedCertificate struct {
+	Name string
+	Err  error
+}
+
+// Error implements [error].
+func (e ErrUntrustedCertificate) Error() string {
+	return fmt.Sprintf("untrusted certificate of %s: %s", e.Name, e.Err)
+}
+
+// Unwrap returns the verification error.
+func (e ErrUntrustedCertificate) Unwrap() error {
+	return e.Err
+}
+
 // AddressGetter TODO
 type AddressGetter interface {
-	GetAddress(ctx context.Context, postalCode string) (string, error)
+	GetAddress(ctx context.Context, postalCode string) (Address, error)
 }
 
 // TemperatureGetter TODO
 type TemperatureGetter interface {
-	GetTemperature(ctx context.Context, location string) (float64, error)
+	GetTemperature(ctx context.Context, location Location) (float64, error)
+}
+
+// ObservationGetter returns the full current weather observation for a
+// location.
+type ObservationGetter interface {
+	GetObservation(ctx context.Context, location Location) (Observation, error)
+}
+
+// ForecastGetter returns the weather forecast for a location.
+type ForecastGetter interface {
+	GetForecast(ctx context.Context, location Location) (Forecast, error)
 }
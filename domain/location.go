@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"strconv"
+)
+
+// Coordinates is a point in decimal degrees (WGS84).
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// String formats c as "latitude,longitude", the form most weather APIs
+// accept as a query.
+func (c Coordinates) String() string {
+	return strconv.FormatFloat(c.Latitude, 'f', -1, 64) + "," +
+		strconv.FormatFloat(c.Longitude, 'f', -1, 64)
+}
+
+// Location is where the weather is looked up. Providers must prefer
+// Coordinates when present and fall back to the city name otherwise.
+type Location struct {
+	City        string       `json:"city"`
+	UF          string       `json:"uf,omitempty"`
+	Coordinates *Coordinates `json:"coordinates,omitempty"`
+}
+
+// String identifies the location: its coordinates when known, the city name
+// and UF, such as "Bom Jesus, PI", otherwise.
+func (l Location) String() string {
+	if l.Coordinates != nil {
+		return l.Coordinates.String()
+	}
+	if l.UF == "" {
+		return l.City
+	}
+	return l.City + ", " + l.UF
+}
+
+// Key identifies the location for caching and deduplication. Name-only
+// locations include the UF, since city names repeat across states.
+func (l Location) Key() string {
+	if l.Coordinates != nil {
+		return l.Coordinates.String()
+	}
+	return l.City + "/" + l.UF
+}
+
+// Geocoder resolves an address to the coordinates of its municipality.
+type Geocoder interface {
+	Geocode(ctx context.Context, address Address) (Coordinates, error)
+}
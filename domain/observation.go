@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Observation is a snapshot of the current weather at a location. Fields a
+// provider does not report are left zeroed.
+type Observation struct {
+	TempC         float64   `json:"temp_C"`
+	FeelsLikeC    float64   `json:"feels_like_C"`
+	Humidity      float64   `json:"humidity"`
+	PressureHPa   float64   `json:"pressure_hPa"`
+	WindSpeedKmph float64   `json:"wind_speed_kmph"`
+	WindDirDegree float64   `json:"wind_dir_degree"`
+	WindDir       string    `json:"wind_dir,omitempty"`
+	UVIndex       float64   `json:"uv_index"`
+	VisibilityKm  float64   `json:"visibility_km"`
+	CloudCover    float64   `json:"cloud_cover"`
+	PrecipMM      float64   `json:"precip_mm"`
+	Description   string    `json:"description,omitempty"`
+	ObservedAt    time.Time `json:"observed_at"`
+	Source        string    `json:"source,omitempty"`
+
+	// Stale is set when the observation is served from a cache after its
+	// freshness lifetime. Age is how long ago the provider observed it, or,
+	// when the provider reports no observation time, fetched it.
+	Stale bool          `json:"stale,omitempty"`
+	Age   time.Duration `json:"-"`
+}
+
+// AsObservationGetter returns tg itself when it already implements
+// [ObservationGetter]. Otherwise it wraps tg so that only the temperature and
+// the time of the call are filled in.
+func AsObservationGetter(tg TemperatureGetter) ObservationGetter {
+	if og, ok := tg.(ObservationGetter); ok {
+		return og
+	}
+	return temperatureOnly{tg: tg}
+}
+
+type temperatureOnly struct {
+	tg TemperatureGetter
+}
+
+func (t temperatureOnly) GetObservation(ctx context.Context, location Location) (Observation, error) {
+	c, err := t.tg.GetTemperature(ctx, location)
+	if err != nil {
+		return Observation{}, err
+	}
+	return Observation{TempC: c, ObservedAt: time.Now().UTC()}, nil
+}
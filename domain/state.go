@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// stateNames maps each UF to the names geocoding APIs give its state.
+var stateNames = map[string][]string{
+	"AC": {"Acre"}, "AL": {"Alagoas"}, "AP": {"Amapá"}, "AM": {"Amazonas"},
+	"BA": {"Bahia"}, "CE": {"Ceará"}, "DF": {"Distrito Federal", "Federal District"},
+	"ES": {"Espírito Santo"}, "GO": {"Goiás"}, "MA": {"Maranhão"},
+	"MT": {"Mato Grosso"}, "MS": {"Mato Grosso do Sul"}, "MG": {"Minas Gerais"},
+	"PA": {"Pará"}, "PB": {"Paraíba"}, "PR": {"Paraná"}, "PE": {"Pernambuco"},
+	"PI": {"Piauí"}, "RJ": {"Rio de Janeiro"}, "RN": {"Rio Grande do Norte"},
+	"RS": {"Rio Grande do Sul"}, "RO": {"Rondônia"}, "RR": {"Roraima"},
+	"SC": {"Santa Catarina"}, "SP": {"São Paulo"}, "SE": {"Sergipe"},
+	"TO": {"Tocantins"},
+}
+
+// InState reports whether state, the name of a Brazilian state as given by a
+// geocoding API, such as "Piauí" or "State of Piauí", is the state of uf.
+// Case and accents are ignored.
+func InState(state, uf string) bool {
+	state = foldName(state)
+	for _, prefix := range []string{"state of ", "estado de ", "estado do ", "estado da "} {
+		state = strings.TrimPrefix(state, prefix)
+	}
+
+	for _, name := range stateNames[strings.ToUpper(strings.TrimSpace(uf))] {
+		if foldName(name) == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+func foldName(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	if folded, _, err := transform.String(t, s); err == nil {
+		s = folded
+	}
+
+	return strings.ToLower(strings.TrimSpace(s))
+}
@@ -0,0 +1,112 @@
+// Package brasilapi implements [domain.AddressGetter] using the BrasilAPI CEP
+// endpoint.
+package brasilapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+)
+
+const baseURL = "https://brasilapi.com.br/api/cep/v1/"
+
+// ErrStatusCode is returned when BrasilAPI answers with an unexpected status.
+// Message carries the error message from the response body, when any.
+type ErrStatusCode struct {
+	Status  int
+	Message string
+}
+
+// Error implements [error].
+func (e ErrStatusCode) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status code %d", e.Status)
+	}
+	return fmt.Sprintf("unexpected status code %d: %s", e.Status, e.Message)
+}
+
+// BrasilAPI is a [domain.AddressGetter] backed by BrasilAPI.
+type BrasilAPI struct {
+	cl *http.Client
+}
+
+// NewAddressGetter returns a new implementation of [domain.AddressGetter].
+func NewAddressGetter(cl *http.Client) domain.AddressGetter {
+	return &BrasilAPI{cl: cl}
+}
+
+// GetAddress implements [domain.AddressGetter].
+func (a *BrasilAPI) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "get-address")
+	defer span.End()
+
+	u, err := url.JoinPath(baseURL, postalCode)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("mounting url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := a.cl.Do(req)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("doing request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return domain.Address{}, domain.ErrPostalCodeNotFound
+	default:
+		var body errorBody
+		_ = json.NewDecoder(res.Body).Decode(&body)
+		return domain.Address{}, ErrStatusCode{Status: res.StatusCode, Message: body.Message}
+	}
+
+	var body brasilAPI
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return domain.Address{}, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return body.toAddress(postalCode), nil
+}
+
+type brasilAPI struct {
+	Cep          string `json:"cep"`
+	State        string `json:"state"`
+	City         string `json:"city"`
+	Neighborhood string `json:"neighborhood"`
+	Street       string `json:"street"`
+	Service      string `json:"service"`
+}
+
+func (b brasilAPI) toAddress(postalCode string) domain.Address {
+	cep := domain.NormalizePostalCode(b.Cep)
+	if cep == "" {
+		cep = domain.NormalizePostalCode(postalCode)
+	}
+
+	return domain.Address{
+		PostalCode:   cep,
+		Street:       b.Street,
+		Neighborhood: b.Neighborhood,
+		City:         b.City,
+		UF:           b.State,
+		Source:       "brasilapi",
+	}
+}
+
+type errorBody struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
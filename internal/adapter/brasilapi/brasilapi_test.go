@@ -0,0 +1,116 @@
+package brasilapi_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/brasilapi"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type BrasilAPISuite struct {
+	suite.Suite
+}
+
+func TestBrasilAPISuite(t *testing.T) {
+	suite.Run(t, new(BrasilAPISuite))
+}
+
+func (s *BrasilAPISuite) newGetter(rt roundTripperFunc) *http.Client {
+	return &http.Client{Transport: rt}
+}
+
+func (s *BrasilAPISuite) TestFailedRequest() {
+	expectedErr := errors.New("request failed")
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, expectedErr
+	})
+
+	ag := brasilapi.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorIs(err, expectedErr)
+	s.Empty(addr)
+}
+
+func (s *BrasilAPISuite) TestNonOKStatusCode() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Body:       io.NopCloser(strings.NewReader(`{"name":"CepPromiseError","message":"Erro interno","type":"service_error"}`)),
+		}, nil
+	})
+
+	ag := brasilapi.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorAs(err, &brasilapi.ErrStatusCode{})
+	s.Empty(addr)
+}
+
+func (s *BrasilAPISuite) TestPostalCodeNotFound() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader(`{"name":"CepPromiseError","message":"Todos os serviços de CEP retornaram erro.","type":"service_error"}`)),
+		}, nil
+	})
+
+	ag := brasilapi.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "99999999")
+
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Empty(addr)
+}
+
+func (s *BrasilAPISuite) TestInvalidJSON() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("{]")),
+		}, nil
+	})
+
+	ag := brasilapi.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.Error(err)
+	s.Empty(addr)
+}
+
+func (s *BrasilAPISuite) TestSuccessfulAddress() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		s.Equal("https://brasilapi.com.br/api/cep/v1/01001000", r.URL.String())
+
+		body := `{"cep":"01001000","state":"SP","city":"São Paulo",` +
+			`"neighborhood":"Sé","street":"Praça da Sé","service":"viacep"}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	ag := brasilapi.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal(domain.Address{
+		PostalCode:   "01001000",
+		Street:       "Praça da Sé",
+		Neighborhood: "Sé",
+		City:         "São Paulo",
+		UF:           "SP",
+		Source:       "brasilapi",
+	}, addr)
+}
@@ -0,0 +1,277 @@
+// Package breaker decorates [domain.AddressGetter] and
+// [domain.TemperatureGetter] with a circuit breaker, so a degraded provider
+// fails fast with [domain.ErrCircuitOpen] instead of tying up every request.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// State is the state of a circuit.
+type State int
+
+// The states of a circuit. A closed circuit lets every call through. An open
+// one rejects every call until its cooldown ends, when it becomes half-open
+// and lets a few probe calls through: their success closes it again, any
+// failure reopens it.
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+// String implements [fmt.Stringer].
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Settings configures a circuit.
+type Settings struct {
+	// FailureRatio is the ratio of failed calls in a window that opens the
+	// circuit.
+	FailureRatio float64
+	// MinRequests is how many calls a window needs before its failure ratio
+	// is considered.
+	MinRequests int
+	// Window is how long calls are counted for while closed; the counts are
+	// reset at the end of every window.
+	Window time.Duration
+	// Cooldown is how long the circuit stays open.
+	Cooldown time.Duration
+	// HalfOpenRequests is how many probe calls are let through, and must
+	// succeed, while half-open.
+	HalfOpenRequests int
+}
+
+// DefaultSettings are the settings used for the zero fields of the
+// [Settings] given to a [Breaker].
+var DefaultSettings = Settings{
+	FailureRatio:     0.5,
+	MinRequests:      10,
+	Window:           30 * time.Second,
+	Cooldown:         15 * time.Second,
+	HalfOpenRequests: 1,
+}
+
+// Breaker is a circuit breaker. It is safe for concurrent use.
+type Breaker struct {
+	name     string
+	settings Settings
+	changes  metric.Int64Counter
+
+	mu          sync.Mutex
+	state       State
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+// New returns a closed [Breaker] identified by name in errors, spans and
+// metrics.
+func New(name string, settings Settings) *Breaker {
+	changes, _ := otel.Meter("service-b").Int64Counter(
+		"breaker.state_changes",
+		metric.WithDescription("Circuit breaker state transitions"),
+	)
+
+	return &Breaker{
+		name:     name,
+		settings: withDefaults(settings),
+		changes:  changes,
+		state:    Closed,
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(context.Background(), time.Now())
+	return b.state
+}
+
+// Do calls fn unless the circuit is open, in which case it returns
+// [domain.ErrCircuitOpen]. Any error returned by fn is a failure, timeouts
+// included, except [domain.ErrPostalCodeNotFound] and
+// [domain.ErrLocationNotFound], which are answers, and errors after ctx was
+// cancelled or ran past its deadline, [domain.ErrRateLimited] and
+// [domain.ErrBulkheadFull], which say nothing about the provider and are not
+// counted at all. A timeout only counts when the caller still had time, as
+// with the timeout of the HTTP client.
+func (b *Breaker) Do(ctx context.Context, fn func(context.Context) error) error {
+	if err := b.allow(ctx); err != nil {
+		trace.SpanFromContext(ctx).AddEvent("breaker-rejected", trace.WithAttributes(
+			attribute.String("breaker.name", b.name),
+		))
+		return err
+	}
+
+	err := fn(ctx)
+	b.record(ctx, classify(ctx, err))
+
+	return err
+}
+
+type outcome int
+
+const (
+	success outcome = iota
+	failure
+	ignored
+)
+
+func classify(ctx context.Context, err error) outcome {
+	var (
+		limited domain.ErrRateLimited
+		full    domain.ErrBulkheadFull
+	)
+
+	switch {
+	case err == nil, errors.Is(err, domain.ErrPostalCodeNotFound), errors.Is(err, domain.ErrLocationNotFound):
+		return success
+	case ctx.Err() != nil:
+		// the caller gave up or ran out of time, which says nothing about
+		// the provider
+		return ignored
+	case errors.As(err, &limited), errors.As(err, &full):
+		// refused on our side, without calling the provider
+		return ignored
+	default:
+		return failure
+	}
+}
+
+func (b *Breaker) allow(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.advance(ctx, now)
+
+	switch b.state {
+	case Open:
+		return domain.ErrCircuitOpen{Name: b.name, RetryAfter: b.openedAt.Add(b.settings.Cooldown).Sub(now)}
+	case HalfOpen:
+		if b.probes >= b.settings.HalfOpenRequests {
+			return domain.ErrCircuitOpen{Name: b.name, RetryAfter: b.settings.Cooldown}
+		}
+		b.probes++
+	}
+
+	return nil
+}
+
+func (b *Breaker) record(ctx context.Context, o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.advance(ctx, now)
+
+	switch b.state {
+	case Closed:
+		if o == ignored {
+			return
+		}
+		b.requests++
+		if o == failure {
+			b.failures++
+		}
+		if b.requests >= b.settings.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.settings.FailureRatio {
+			b.setState(ctx, Open, now)
+		}
+	case HalfOpen:
+		switch o {
+		case ignored:
+			// let another call probe in its place
+			b.probes--
+			return
+		case failure:
+			b.setState(ctx, Open, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.settings.HalfOpenRequests {
+			b.setState(ctx, Closed, now)
+		}
+	}
+}
+
+// advance moves the circuit along with time: an open circuit becomes
+// half-open after its cooldown and a closed one starts a new window.
+func (b *Breaker) advance(ctx context.Context, now time.Time) {
+	switch b.state {
+	case Open:
+		if !now.Before(b.openedAt.Add(b.settings.Cooldown)) {
+			b.setState(ctx, HalfOpen, now)
+		}
+	case Closed:
+		if !now.Before(b.windowStart.Add(b.settings.Window)) {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	}
+}
+
+func (b *Breaker) setState(ctx context.Context, state State, now time.Time) {
+	from := b.state
+	b.state = state
+
+	switch state {
+	case Open:
+		b.openedAt = now
+	case HalfOpen:
+		b.probes, b.successes = 0, 0
+	case Closed:
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("breaker.name", b.name),
+		attribute.String("breaker.from", from.String()),
+		attribute.String("breaker.to", state.String()),
+	}
+	trace.SpanFromContext(ctx).AddEvent("breaker-state-change", trace.WithAttributes(attrs...))
+	b.changes.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+func withDefaults(s Settings) Settings {
+	if s.FailureRatio <= 0 {
+		s.FailureRatio = DefaultSettings.FailureRatio
+	}
+	if s.MinRequests <= 0 {
+		s.MinRequests = DefaultSettings.MinRequests
+	}
+	if s.Window <= 0 {
+		s.Window = DefaultSettings.Window
+	}
+	if s.Cooldown <= 0 {
+		s.Cooldown = DefaultSettings.Cooldown
+	}
+	if s.HalfOpenRequests <= 0 {
+		s.HalfOpenRequests = DefaultSettings.HalfOpenRequests
+	}
+	return s
+}
@@ -0,0 +1,317 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/breaker"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var errUpstream = errors.New("upstream unavailable")
+
+type mockAddressGetter struct {
+	err   error
+	calls int
+}
+
+func (m *mockAddressGetter) GetAddress(_ context.Context, postalCode string) (domain.Address, error) {
+	m.calls++
+	if m.err != nil {
+		return domain.Address{}, m.err
+	}
+	return domain.Address{PostalCode: postalCode, City: "São Paulo"}, nil
+}
+
+type mockObservationGetter struct {
+	err   error
+	calls int
+}
+
+func (m *mockObservationGetter) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := m.GetObservation(ctx, location)
+	return obs.TempC, err
+}
+
+func (m *mockObservationGetter) GetObservation(_ context.Context, _ domain.Location) (domain.Observation, error) {
+	m.calls++
+	return domain.Observation{TempC: 25}, m.err
+}
+
+type BreakerSuite struct {
+	suite.Suite
+}
+
+func TestBreakerSuite(t *testing.T) {
+	suite.Run(t, new(BreakerSuite))
+}
+
+var settings = breaker.Settings{
+	FailureRatio: 0.5,
+	MinRequests:  4,
+	Window:       time.Minute,
+	Cooldown:     50 * time.Millisecond,
+}
+
+func (s *BreakerSuite) TestOpensOnFailureRatio() {
+	next := &mockAddressGetter{err: errUpstream}
+	ag := breaker.NewAddressGetter(next, "viacep", settings)
+
+	for range 4 {
+		_, err := ag.GetAddress(context.Background(), "01001000")
+		s.ErrorIs(err, errUpstream)
+	}
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+
+	var open domain.ErrCircuitOpen
+	s.Require().ErrorAs(err, &open)
+	s.Equal("viacep", open.Name)
+	s.Positive(open.RetryAfter)
+	s.LessOrEqual(open.RetryAfter, settings.Cooldown)
+	s.Equal(4, next.calls)
+}
+
+func (s *BreakerSuite) TestStaysClosedBelowRatio() {
+	next := &mockAddressGetter{}
+	ag := breaker.NewAddressGetter(next, "viacep", settings)
+
+	for i := range 10 {
+		next.err = nil
+		if i%3 == 1 {
+			next.err = errUpstream
+		}
+		_, _ = ag.GetAddress(context.Background(), "01001000")
+	}
+
+	s.Equal(10, next.calls)
+}
+
+func (s *BreakerSuite) TestNotFoundIsNotAFailure() {
+	next := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	ag := breaker.NewAddressGetter(next, "viacep", settings)
+
+	for range 10 {
+		_, err := ag.GetAddress(context.Background(), "99999999")
+		s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	}
+
+	s.Equal(10, next.calls)
+}
+
+func (s *BreakerSuite) TestCancellationIsNotCounted() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	next := &mockAddressGetter{err: context.Canceled}
+	ag := breaker.NewAddressGetter(next, "viacep", settings)
+
+	for range 10 {
+		_, err := ag.GetAddress(ctx, "01001000")
+		s.ErrorIs(err, context.Canceled)
+	}
+
+	s.Equal(10, next.calls)
+}
+
+// timeoutError is the error of a client giving up on a connection.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func (s *BreakerSuite) TestTimeoutsAreFailures() {
+	tests := map[string]error{
+		"client timeout": &url.Error{Op: "Get", URL: "https://viacep.com.br", Err: timeoutError{}},
+		"deadline below": fmt.Errorf("doing request: %w", context.DeadlineExceeded),
+	}
+
+	for name, err := range tests {
+		s.Run(name, func() {
+			next := &mockAddressGetter{err: err}
+			ag := breaker.NewAddressGetter(next, "viacep", settings)
+
+			for range 5 {
+				_, _ = ag.GetAddress(context.Background(), "01001000")
+			}
+
+			s.Equal(4, next.calls)
+		})
+	}
+}
+
+func (s *BreakerSuite) TestCallerDeadlineIsNotCounted() {
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+
+	// coalescing cancels the shared call once its callers ran out of time
+	coalesced, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(context.DeadlineExceeded)
+
+	tests := map[string]struct {
+		ctx context.Context
+		err error
+	}{
+		"caller deadline": {ctx: expired, err: fmt.Errorf("doing request: %w", context.DeadlineExceeded)},
+		"client timeout":  {ctx: expired, err: &url.Error{Op: "Get", URL: "https://viacep.com.br", Err: timeoutError{}}},
+		"coalesced":       {ctx: coalesced, err: fmt.Errorf("doing request: %w", context.Canceled)},
+	}
+
+	for name, tt := range tests {
+		s.Run(name, func() {
+			next := &mockAddressGetter{err: tt.err}
+			ag := breaker.NewAddressGetter(next, "viacep", settings)
+
+			for range 10 {
+				_, _ = ag.GetAddress(tt.ctx, "01001000")
+			}
+
+			s.Equal(10, next.calls)
+		})
+	}
+}
+
+func (s *BreakerSuite) TestLocationNotFoundIsNotAFailure() {
+	next := &mockObservationGetter{err: fmt.Errorf("geocoding: %w", domain.ErrLocationNotFound)}
+	tg := breaker.NewTemperatureGetter(next, "openmeteo", settings)
+
+	for range 10 {
+		_, err := tg.GetTemperature(context.Background(), domain.Location{City: "Nowhere"})
+		s.ErrorIs(err, domain.ErrLocationNotFound)
+	}
+
+	s.Equal(10, next.calls)
+}
+
+func (s *BreakerSuite) TestRateLimitIsNotCounted() {
+	next := &mockAddressGetter{err: domain.ErrRateLimited{Name: "viacep", RetryAfter: time.Second}}
+	ag := breaker.NewAddressGetter(next, "viacep", settings)
+
+	for range 10 {
+		_, _ = ag.GetAddress(context.Background(), "01001000")
+	}
+
+	s.Equal(10, next.calls)
+}
+
+func (s *BreakerSuite) TestBulkheadFullIsNotCounted() {
+	next := &mockAddressGetter{err: domain.ErrBulkheadFull{Name: "viacep"}}
+	ag := breaker.NewAddressGetter(next, "viacep", settings)
+
+	for range 10 {
+		_, _ = ag.GetAddress(context.Background(), "01001000")
+	}
+
+	s.Equal(10, next.calls)
+}
+
+func (s *BreakerSuite) TestHalfOpenCloses() {
+	b := breaker.New("wttr", settings)
+	for range 4 {
+		_ = b.Do(context.Background(), func(context.Context) error { return errUpstream })
+	}
+	s.Equal(breaker.Open, b.State())
+
+	time.Sleep(settings.Cooldown)
+	s.Equal(breaker.HalfOpen, b.State())
+
+	err := b.Do(context.Background(), func(context.Context) error { return nil })
+
+	s.NoError(err)
+	s.Equal(breaker.Closed, b.State())
+}
+
+func (s *BreakerSuite) TestHalfOpenReopens() {
+	b := breaker.New("wttr", settings)
+	for range 4 {
+		_ = b.Do(context.Background(), func(context.Context) error { return errUpstream })
+	}
+
+	time.Sleep(settings.Cooldown)
+	err := b.Do(context.Background(), func(context.Context) error { return errUpstream })
+
+	s.ErrorIs(err, errUpstream)
+	s.Equal(breaker.Open, b.State())
+}
+
+func (s *BreakerSuite) TestHalfOpenLimitsProbes() {
+	b := breaker.New("wttr", settings)
+	for range 4 {
+		_ = b.Do(context.Background(), func(context.Context) error { return errUpstream })
+	}
+	time.Sleep(settings.Cooldown)
+
+	release := make(chan struct{})
+	probing := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(context.Background(), func(context.Context) error {
+			close(probing)
+			<-release
+			return nil
+		})
+	}()
+	<-probing
+
+	err := b.Do(context.Background(), func(context.Context) error { return nil })
+
+	var open domain.ErrCircuitOpen
+	s.ErrorAs(err, &open)
+
+	close(release)
+	s.NoError(<-done)
+	s.Equal(breaker.Closed, b.State())
+}
+
+func (s *BreakerSuite) TestTemperatureGetter() {
+	next := &mockObservationGetter{err: errUpstream}
+	tg := breaker.NewTemperatureGetter(next, "wttr", settings)
+	og, ok := tg.(domain.ObservationGetter)
+	s.Require().True(ok)
+
+	for range 4 {
+		_, err := og.GetObservation(context.Background(), domain.Location{City: "São Paulo"})
+		s.ErrorIs(err, errUpstream)
+	}
+	_, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	var open domain.ErrCircuitOpen
+	s.ErrorAs(err, &open)
+	s.Equal(4, next.calls)
+}
+
+func (s *BreakerSuite) TestStateChangeMetric() {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(noop.NewMeterProvider())
+
+	b := breaker.New("wttr", settings)
+	for range 4 {
+		_ = b.Do(context.Background(), func(context.Context) error { return errUpstream })
+	}
+
+	var rm metricdata.ResourceMetrics
+	s.Require().NoError(reader.Collect(context.Background(), &rm))
+	s.Require().Len(rm.ScopeMetrics, 1)
+	s.Require().Len(rm.ScopeMetrics[0].Metrics, 1)
+
+	m := rm.ScopeMetrics[0].Metrics[0]
+	s.Equal("breaker.state_changes", m.Name)
+
+	sum, ok := m.Data.(metricdata.Sum[int64])
+	s.Require().True(ok)
+	s.Require().Len(sum.DataPoints, 1)
+	s.Equal(int64(1), sum.DataPoints[0].Value)
+
+	to, _ := sum.DataPoints[0].Attributes.Value("breaker.to")
+	s.Equal("open", to.AsString())
+}
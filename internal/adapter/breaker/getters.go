@@ -0,0 +1,82 @@
+package breaker
+
+import (
+	"context"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+)
+
+// AddressGetter guards another [domain.AddressGetter] with a [Breaker].
+type AddressGetter struct {
+	next    domain.AddressGetter
+	breaker *Breaker
+}
+
+// NewAddressGetter returns a [domain.AddressGetter] that calls next through
+// a circuit named name.
+func NewAddressGetter(next domain.AddressGetter, name string, settings Settings) domain.AddressGetter {
+	return New(name, settings).AddressGetter(next)
+}
+
+// AddressGetter returns a [domain.AddressGetter] that calls next through b,
+// so that the circuit outlives the getter.
+func (b *Breaker) AddressGetter(next domain.AddressGetter) domain.AddressGetter {
+	return &AddressGetter{next: next, breaker: b}
+}
+
+// GetAddress implements [domain.AddressGetter].
+func (b *AddressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	var addr domain.Address
+
+	err := b.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		addr, err = b.next.GetAddress(ctx, postalCode)
+		return err
+	})
+
+	return addr, err
+}
+
+// TemperatureGetter guards another [domain.TemperatureGetter] with a
+// [Breaker].
+type TemperatureGetter struct {
+	next    domain.ObservationGetter
+	breaker *Breaker
+}
+
+// NewTemperatureGetter returns a [domain.TemperatureGetter] that calls next
+// through a circuit named name. The returned value also implements
+// [domain.ObservationGetter].
+func NewTemperatureGetter(next domain.TemperatureGetter, name string, settings Settings) domain.TemperatureGetter {
+	return New(name, settings).TemperatureGetter(next)
+}
+
+// TemperatureGetter returns a [domain.TemperatureGetter] that calls next
+// through b, so that the circuit outlives the getter. The returned value also
+// implements [domain.ObservationGetter].
+func (b *Breaker) TemperatureGetter(next domain.TemperatureGetter) domain.TemperatureGetter {
+	return &TemperatureGetter{next: domain.AsObservationGetter(next), breaker: b}
+}
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (b *TemperatureGetter) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := b.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter].
+func (b *TemperatureGetter) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	var obs domain.Observation
+
+	err := b.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		obs, err = b.next.GetObservation(ctx, location)
+		return err
+	})
+
+	return obs, err
+}
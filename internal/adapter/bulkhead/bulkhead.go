@@ -0,0 +1,157 @@
+// Package bulkhead implements an [http.RoundTripper] bounding how many
+// requests are in flight to a provider, so that a slow provider ties up its
+// own connections and goroutines only, never those of the others.
+package bulkhead
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Settings configures the bulkhead of a provider.
+type Settings struct {
+	// MaxConcurrent is how many requests may be in flight at once. A
+	// request is in flight until its response body is closed.
+	MaxConcurrent int
+	// QueueTimeout is how long a request waits for another to finish when
+	// MaxConcurrent are in flight, before failing with
+	// [domain.ErrBulkheadFull].
+	QueueTimeout time.Duration
+}
+
+// DefaultSettings are the [Settings] used when a provider has none.
+var DefaultSettings = Settings{MaxConcurrent: 20, QueueTimeout: 500 * time.Millisecond}
+
+// Transport is an [http.RoundTripper] enforcing the bulkhead of a provider.
+type Transport struct {
+	next     http.RoundTripper
+	name     string
+	settings Settings
+	slots    chan struct{}
+	attrs    metric.MeasurementOption
+
+	inFlight metric.Int64UpDownCounter
+	queued   metric.Int64UpDownCounter
+	rejected metric.Int64Counter
+}
+
+// NewTransport returns a [Transport] sending the requests for the provider
+// name through next, or [http.DefaultTransport] if next is nil.
+func NewTransport(next http.RoundTripper, name string, settings Settings) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if settings.MaxConcurrent <= 0 {
+		settings.MaxConcurrent = DefaultSettings.MaxConcurrent
+	}
+
+	meter := otel.Meter("service-b")
+	inFlight, _ := meter.Int64UpDownCounter(
+		"bulkhead.in_flight",
+		metric.WithDescription("Requests in flight to a provider"),
+	)
+	queued, _ := meter.Int64UpDownCounter(
+		"bulkhead.queued",
+		metric.WithDescription("Requests waiting for the bulkhead of a provider"),
+	)
+	rejected, _ := meter.Int64Counter(
+		"bulkhead.rejected",
+		metric.WithDescription("Requests rejected by the full bulkhead of a provider"),
+	)
+
+	return &Transport{
+		next:     next,
+		name:     name,
+		settings: settings,
+		slots:    make(chan struct{}, settings.MaxConcurrent),
+		attrs:    metric.WithAttributes(attribute.String("provider", name)),
+		inFlight: inFlight,
+		queued:   queued,
+		rejected: rejected,
+	}
+}
+
+// RoundTrip implements [http.RoundTripper]. Requests that find the bulkhead
+// full for longer than the queue timeout fail with [domain.ErrBulkheadFull].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if err := t.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.release(ctx)
+		return nil, err
+	}
+
+	res.Body = &releasingBody{ReadCloser: res.Body, release: func() { t.release(ctx) }}
+	return res, nil
+}
+
+// InFlight returns how many requests are in flight.
+func (t *Transport) InFlight() int {
+	return len(t.slots)
+}
+
+func (t *Transport) acquire(ctx context.Context) error {
+	select {
+	case t.slots <- struct{}{}:
+		t.inFlight.Add(ctx, 1, t.attrs)
+		return nil
+	default:
+	}
+
+	t.queued.Add(ctx, 1, t.attrs)
+	defer t.queued.Add(ctx, -1, t.attrs)
+
+	trace.SpanFromContext(ctx).AddEvent("bulkhead-queued", trace.WithAttributes(
+		attribute.String("bulkhead.provider", t.name),
+	))
+
+	timer := time.NewTimer(t.settings.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case t.slots <- struct{}{}:
+		t.inFlight.Add(ctx, 1, t.attrs)
+		return nil
+	case <-timer.C:
+		trace.SpanFromContext(ctx).AddEvent("bulkhead-rejected", trace.WithAttributes(
+			attribute.String("bulkhead.provider", t.name),
+		))
+		t.rejected.Add(ctx, 1, t.attrs)
+		return domain.ErrBulkheadFull{Name: t.name}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Transport) release(ctx context.Context) {
+	<-t.slots
+	t.inFlight.Add(context.WithoutCancel(ctx), -1, t.attrs)
+}
+
+// releasingBody releases the slot of a request when its body is closed, since
+// the connection is in use until then.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
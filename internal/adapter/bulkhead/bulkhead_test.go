@@ -0,0 +1,136 @@
+package bulkhead_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/bulkhead"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type BulkheadSuite struct {
+	suite.Suite
+}
+
+func TestBulkheadSuite(t *testing.T) {
+	suite.Run(t, new(BulkheadSuite))
+}
+
+var ok = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+})
+
+func (s *BulkheadSuite) get(ctx context.Context, rt http.RoundTripper) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	s.Require().NoError(err)
+
+	return rt.RoundTrip(req)
+}
+
+func (s *BulkheadSuite) TestSlotHeldUntilBodyClosed() {
+	rt := bulkhead.NewTransport(ok, "wttr", bulkhead.Settings{MaxConcurrent: 1, QueueTimeout: 10 * time.Millisecond})
+
+	res, err := s.get(context.Background(), rt)
+	s.Require().NoError(err)
+	s.Equal(1, rt.InFlight())
+
+	_, err = s.get(context.Background(), rt)
+	var full domain.ErrBulkheadFull
+	s.Require().ErrorAs(err, &full)
+	s.Equal("wttr", full.Name)
+
+	s.NoError(res.Body.Close())
+	s.NoError(res.Body.Close())
+	s.Equal(0, rt.InFlight())
+
+	res, err = s.get(context.Background(), rt)
+	s.Require().NoError(err)
+	res.Body.Close()
+}
+
+func (s *BulkheadSuite) TestQueuedRequestGetsFreedSlot() {
+	rt := bulkhead.NewTransport(ok, "wttr", bulkhead.Settings{MaxConcurrent: 1, QueueTimeout: time.Second})
+
+	res, err := s.get(context.Background(), rt)
+	s.Require().NoError(err)
+
+	time.AfterFunc(20*time.Millisecond, func() { res.Body.Close() })
+
+	start := time.Now()
+	res, err = s.get(context.Background(), rt)
+	s.Require().NoError(err)
+	res.Body.Close()
+
+	s.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+	s.Less(time.Since(start), time.Second)
+}
+
+func (s *BulkheadSuite) TestQueueRespectsContext() {
+	rt := bulkhead.NewTransport(ok, "wttr", bulkhead.Settings{MaxConcurrent: 1, QueueTimeout: time.Minute})
+
+	res, err := s.get(context.Background(), rt)
+	s.Require().NoError(err)
+	defer res.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = s.get(ctx, rt)
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *BulkheadSuite) TestFailedRequestReleasesSlot() {
+	failing := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, context.DeadlineExceeded
+	})
+	rt := bulkhead.NewTransport(failing, "wttr", bulkhead.Settings{MaxConcurrent: 1})
+
+	_, err := s.get(context.Background(), rt)
+	s.Error(err)
+	s.Equal(0, rt.InFlight())
+}
+
+func (s *BulkheadSuite) TestBoundsConcurrency() {
+	var (
+		mu      sync.Mutex
+		current int
+		peak    int
+	)
+	slow := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		current++
+		peak = max(peak, current)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		current--
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	})
+	rt := bulkhead.NewTransport(slow, "wttr", bulkhead.Settings{MaxConcurrent: 3, QueueTimeout: time.Second})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			res, err := s.get(context.Background(), rt)
+			if s.NoError(err) {
+				res.Body.Close()
+			}
+		})
+	}
+	wg.Wait()
+
+	s.Equal(3, peak)
+}
@@ -0,0 +1,421 @@
+package cache
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	entriesBucket = []byte("entries")
+	hitsBucket    = []byte("hits")
+)
+
+// compactTxSize bounds the size of the transactions copying the entries
+// into a compacted file.
+const compactTxSize = 64 << 20
+
+// BoltOptions configures a [Bolt] store.
+type BoltOptions struct {
+	// CompactInterval is how often expired entries are removed and the file
+	// is compacted. Zero disables the periodic compaction; [Bolt.Purge] and
+	// [Bolt.Compact] can still be called directly.
+	CompactInterval time.Duration
+}
+
+// Bolt is a [Store] kept in a single file, so its entries survive restarts.
+// It also counts how many times every key was looked up, through the store
+// returned by [Bolt.CountHits], which [Bolt.Preload] uses to warm a faster
+// store with the hottest entries.
+type Bolt struct {
+	path string
+
+	// dbMu guards db, which is replaced by the compacted file
+	dbMu sync.RWMutex
+	db   *bolt.DB
+
+	mu   sync.Mutex
+	hits map[string]uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OpenBolt opens, creating it if needed, the store kept at path.
+func OpenBolt(path string, opts BoltOptions) (*Bolt, error) {
+	db, err := openBoltDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bolt{
+		path: path,
+		db:   db,
+		hits: make(map[string]uint64),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	if opts.CompactInterval > 0 {
+		go b.compactEvery(opts.CompactInterval)
+	} else {
+		close(b.done)
+	}
+
+	return b, nil
+}
+
+func openBoltDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{entriesBucket, hitsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("creating buckets: %w", err)
+	}
+
+	return db, nil
+}
+
+// Get implements [Store].
+func (b *Bolt) Get(_ context.Context, key string) ([]byte, error) {
+	b.dbMu.RLock()
+	defer b.dbMu.RUnlock()
+
+	var value []byte
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(entriesBucket).Get([]byte(key))
+		if v == nil {
+			return ErrMiss
+		}
+
+		expiresAt, rest := decodeRecord(v)
+		if time.Now().After(expiresAt) {
+			return ErrMiss
+		}
+
+		// v is only valid inside the transaction
+		value = slices.Clone(rest)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Set implements [Store].
+func (b *Bolt) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	b.dbMu.RLock()
+	defer b.dbMu.RUnlock()
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(entriesBucket).Put([]byte(key), encodeRecord(time.Now().Add(ttl), value))
+	})
+}
+
+// CountHits returns a [Store] forwarding to s which counts, in b, every
+// lookup of a key. It is meant for the store in front of b, which serves
+// most lookups, or for b itself when there is none.
+func (b *Bolt) CountHits(s Store) Store {
+	return hitCounter{Store: s, b: b}
+}
+
+type hitCounter struct {
+	Store
+	b *Bolt
+}
+
+// Get implements [Store].
+func (h hitCounter) Get(ctx context.Context, key string) ([]byte, error) {
+	h.b.mu.Lock()
+	h.b.hits[key]++
+	h.b.mu.Unlock()
+
+	return h.Store.Get(ctx, key)
+}
+
+// Purge removes the expired entries and persists the pending hit counts.
+// It returns how many entries were removed. The pages they used are reused
+// for new entries, but only [Bolt.Compact] shrinks the file.
+func (b *Bolt) Purge(_ context.Context) (int, error) {
+	b.dbMu.RLock()
+	defer b.dbMu.RUnlock()
+
+	hits := b.takeHits()
+	now := time.Now()
+	removed := 0
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		entries, counts := tx.Bucket(entriesBucket), tx.Bucket(hitsBucket)
+
+		if err := addHits(counts, hits); err != nil {
+			return err
+		}
+
+		var expired, uncounted [][]byte
+		err := entries.ForEach(func(k, v []byte) error {
+			if expiresAt, _ := decodeRecord(v); now.After(expiresAt) {
+				expired = append(expired, slices.Clone(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// lookups are counted for keys that may never have been stored,
+		// such as those whose lookup failed
+		err = counts.ForEach(func(k, _ []byte) error {
+			if entries.Get(k) == nil {
+				uncounted = append(uncounted, slices.Clone(k))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// keys can not be deleted while iterating
+		for _, k := range expired {
+			if err := entries.Delete(k); err != nil {
+				return err
+			}
+		}
+		for _, k := range append(expired, uncounted...) {
+			if err := counts.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		removed = len(expired)
+		return nil
+	})
+	if err != nil {
+		b.restoreHits(hits)
+		return 0, fmt.Errorf("purging: %w", err)
+	}
+
+	return removed, nil
+}
+
+// Compact rewrites the file without the free pages left by removed and
+// replaced entries, so that it shrinks. Lookups and writes wait for it.
+func (b *Bolt) Compact(_ context.Context) error {
+	b.dbMu.Lock()
+	defer b.dbMu.Unlock()
+
+	tmpPath := b.path + ".compact"
+	_ = os.Remove(tmpPath)
+
+	tmp, err := bolt.Open(tmpPath, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("creating compacted file: %w", err)
+	}
+
+	if err := errors.Join(bolt.Compact(tmp, b.db, compactTxSize), tmp.Close()); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("compacting: %w", err)
+	}
+
+	if err := b.db.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("closing file: %w", err)
+	}
+
+	// the old file is only replaced once it is closed; should the rename
+	// fail, it is opened again as it was
+	renameErr := os.Rename(tmpPath, b.path)
+
+	db, err := openBoltDB(b.path)
+	if err != nil {
+		return fmt.Errorf("reopening compacted file: %w", err)
+	}
+	b.db = db
+
+	if renameErr != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("replacing file: %w", renameErr)
+	}
+
+	return nil
+}
+
+// Preload copies into dst the n unexpired entries read most often, keeping
+// their remaining TTL up to maxTTL, the TTL of the entries of dst. It returns
+// how many entries were copied.
+func (b *Bolt) Preload(ctx context.Context, dst Store, n int, maxTTL time.Duration) (int, error) {
+	type hot struct {
+		key   string
+		value []byte
+		ttl   time.Duration
+		hits  uint64
+	}
+
+	pending := b.snapshotHits()
+	now := time.Now()
+
+	b.dbMu.RLock()
+	defer b.dbMu.RUnlock()
+
+	var all []hot
+	err := b.db.View(func(tx *bolt.Tx) error {
+		counts := tx.Bucket(hitsBucket)
+
+		return tx.Bucket(entriesBucket).ForEach(func(k, v []byte) error {
+			expiresAt, value := decodeRecord(v)
+			if !expiresAt.After(now) {
+				return nil
+			}
+
+			var hits uint64
+			if c := counts.Get(k); len(c) == 8 {
+				hits = binary.BigEndian.Uint64(c)
+			}
+
+			all = append(all, hot{
+				key:   string(k),
+				value: slices.Clone(value),
+				ttl:   min(expiresAt.Sub(now), maxTTL),
+				hits:  hits + pending[string(k)],
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("reading entries: %w", err)
+	}
+
+	slices.SortFunc(all, func(a, b hot) int { return cmp.Compare(b.hits, a.hits) })
+
+	copied := 0
+	for _, h := range all[:min(n, len(all))] {
+		if err := dst.Set(ctx, h.key, h.value, h.ttl); err != nil {
+			return copied, fmt.Errorf("preloading %s: %w", h.key, err)
+		}
+		copied++
+	}
+
+	return copied, nil
+}
+
+// Close stops the periodic compaction, persists the pending hit counts and
+// closes the file.
+func (b *Bolt) Close() error {
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+	<-b.done
+
+	b.dbMu.Lock()
+	defer b.dbMu.Unlock()
+
+	hits := b.takeHits()
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return addHits(tx.Bucket(hitsBucket), hits)
+	})
+	if err != nil {
+		_ = b.db.Close()
+		return fmt.Errorf("saving hits: %w", err)
+	}
+
+	return b.db.Close()
+}
+
+func (b *Bolt) compactEvery(interval time.Duration) {
+	defer close(b.done)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-t.C:
+			if _, err := b.Purge(context.Background()); err != nil {
+				continue
+			}
+			_ = b.Compact(context.Background())
+		}
+	}
+}
+
+func (b *Bolt) takeHits() map[string]uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hits := b.hits
+	b.hits = make(map[string]uint64)
+	return hits
+}
+
+func (b *Bolt) snapshotHits() map[string]uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	hits := make(map[string]uint64, len(b.hits))
+	for k, v := range b.hits {
+		hits[k] = v
+	}
+	return hits
+}
+
+func (b *Bolt) restoreHits(hits map[string]uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for k, v := range hits {
+		b.hits[k] += v
+	}
+}
+
+func addHits(counts *bolt.Bucket, hits map[string]uint64) error {
+	for k, n := range hits {
+		key := []byte(k)
+
+		var total uint64
+		if c := counts.Get(key); len(c) == 8 {
+			total = binary.BigEndian.Uint64(c)
+		}
+
+		if err := counts.Put(key, binary.BigEndian.AppendUint64(nil, total+n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeRecord prefixes value with its expiration, in Unix nanoseconds.
+func encodeRecord(expiresAt time.Time, value []byte) []byte {
+	return append(binary.BigEndian.AppendUint64(nil, uint64(expiresAt.UnixNano())), value...)
+}
+
+func decodeRecord(v []byte) (time.Time, []byte) {
+	if len(v) < 8 {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(v))), v[8:]
+}
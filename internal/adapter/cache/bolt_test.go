@@ -0,0 +1,253 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/cache"
+)
+
+type BoltSuite struct {
+	suite.Suite
+	path string
+}
+
+func TestBoltSuite(t *testing.T) {
+	suite.Run(t, new(BoltSuite))
+}
+
+func (s *BoltSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "cache.db")
+}
+
+func (s *BoltSuite) open() *cache.Bolt {
+	b, err := cache.OpenBolt(s.path, cache.BoltOptions{})
+	s.Require().NoError(err)
+	return b
+}
+
+func (s *BoltSuite) TestGetMissing() {
+	b := s.open()
+	defer b.Close()
+
+	v, err := b.Get(context.Background(), "a")
+
+	s.ErrorIs(err, cache.ErrMiss)
+	s.Nil(v)
+}
+
+func (s *BoltSuite) TestSetAndGet() {
+	b := s.open()
+	defer b.Close()
+
+	s.NoError(b.Set(context.Background(), "a", []byte("1"), time.Minute))
+	v, err := b.Get(context.Background(), "a")
+
+	s.NoError(err)
+	s.Equal([]byte("1"), v)
+}
+
+func (s *BoltSuite) TestExpired() {
+	b := s.open()
+	defer b.Close()
+
+	s.NoError(b.Set(context.Background(), "a", []byte("1"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	v, err := b.Get(context.Background(), "a")
+
+	s.ErrorIs(err, cache.ErrMiss)
+	s.Nil(v)
+}
+
+func (s *BoltSuite) TestSurvivesReopen() {
+	b := s.open()
+	s.NoError(b.Set(context.Background(), "a", []byte("1"), time.Minute))
+	s.NoError(b.Close())
+
+	b = s.open()
+	defer b.Close()
+	v, err := b.Get(context.Background(), "a")
+
+	s.NoError(err)
+	s.Equal([]byte("1"), v)
+}
+
+func (s *BoltSuite) TestPurge() {
+	b := s.open()
+	defer b.Close()
+
+	s.NoError(b.Set(context.Background(), "a", []byte("1"), time.Millisecond))
+	s.NoError(b.Set(context.Background(), "b", []byte("2"), time.Minute))
+	time.Sleep(5 * time.Millisecond)
+
+	removed, err := b.Purge(context.Background())
+	s.NoError(err)
+	s.Equal(1, removed)
+
+	v, err := b.Get(context.Background(), "b")
+	s.NoError(err)
+	s.Equal([]byte("2"), v)
+}
+
+func (s *BoltSuite) TestPeriodicCompaction() {
+	b, err := cache.OpenBolt(s.path, cache.BoltOptions{CompactInterval: 5 * time.Millisecond})
+	s.Require().NoError(err)
+	defer b.Close()
+
+	s.NoError(b.Set(context.Background(), "a", []byte("1"), time.Millisecond))
+
+	time.Sleep(100 * time.Millisecond)
+
+	// nothing left for a manual purge to remove
+	removed, err := b.Purge(context.Background())
+	s.NoError(err)
+	s.Zero(removed)
+}
+
+func (s *BoltSuite) TestCompactShrinksFile() {
+	ctx := context.Background()
+
+	b := s.open()
+	defer b.Close()
+
+	value := make([]byte, 1024)
+	for i := range 2000 {
+		s.Require().NoError(b.Set(ctx, fmt.Sprint(i), value, time.Millisecond))
+	}
+	s.Require().NoError(b.Set(ctx, "kept", []byte("1"), time.Minute))
+	time.Sleep(5 * time.Millisecond)
+
+	_, err := b.Purge(ctx)
+	s.Require().NoError(err)
+	before, err := os.Stat(s.path)
+	s.Require().NoError(err)
+
+	s.Require().NoError(b.Compact(ctx))
+
+	after, err := os.Stat(s.path)
+	s.Require().NoError(err)
+	s.Less(after.Size(), before.Size())
+
+	v, err := b.Get(ctx, "kept")
+	s.NoError(err)
+	s.Equal([]byte("1"), v)
+
+	s.NoError(b.Set(ctx, "new", []byte("2"), time.Minute))
+}
+
+func (s *BoltSuite) TestPreloadHottest() {
+	ctx := context.Background()
+
+	b := s.open()
+	s.NoError(b.Set(ctx, "cold", []byte("1"), time.Minute))
+	s.NoError(b.Set(ctx, "warm", []byte("2"), time.Minute))
+	s.NoError(b.Set(ctx, "hot", []byte("3"), time.Minute))
+	s.NoError(b.Set(ctx, "expired", []byte("4"), time.Millisecond))
+	counted := b.CountHits(b)
+	for range 3 {
+		_, _ = counted.Get(ctx, "hot")
+	}
+	_, _ = counted.Get(ctx, "warm")
+	_, _ = counted.Get(ctx, "expired")
+	time.Sleep(5 * time.Millisecond)
+
+	// hit counts are persisted on close
+	s.NoError(b.Close())
+	b = s.open()
+	defer b.Close()
+
+	lru := cache.NewLRU(10)
+	n, err := b.Preload(ctx, lru, 2, time.Hour)
+
+	s.NoError(err)
+	s.Equal(2, n)
+	s.Equal(2, lru.Len())
+
+	v, err := lru.Get(ctx, "hot")
+	s.NoError(err)
+	s.Equal([]byte("3"), v)
+	_, err = lru.Get(ctx, "warm")
+	s.NoError(err)
+	_, err = lru.Get(ctx, "cold")
+	s.ErrorIs(err, cache.ErrMiss)
+}
+
+func (s *BoltSuite) TestPreloadCountsLookupsInFront() {
+	ctx := context.Background()
+
+	b := s.open()
+	defer b.Close()
+	s.NoError(b.Set(ctx, "hot", []byte("1"), time.Minute))
+	s.NoError(b.Set(ctx, "cold", []byte("2"), time.Minute))
+
+	// the lookups of hot are all served by the store in front of b
+	front := cache.NewLRU(10)
+	s.NoError(front.Set(ctx, "hot", []byte("1"), time.Minute))
+	counted := b.CountHits(front)
+	for range 3 {
+		_, err := counted.Get(ctx, "hot")
+		s.Require().NoError(err)
+	}
+	_, _ = b.Get(ctx, "cold")
+
+	lru := cache.NewLRU(10)
+	n, err := b.Preload(ctx, lru, 1, time.Hour)
+
+	s.NoError(err)
+	s.Equal(1, n)
+	_, err = lru.Get(ctx, "hot")
+	s.NoError(err)
+}
+
+// ttlStore records the TTL of every entry set.
+type ttlStore map[string]time.Duration
+
+func (t ttlStore) Get(context.Context, string) ([]byte, error) { return nil, cache.ErrMiss }
+
+func (t ttlStore) Set(_ context.Context, key string, _ []byte, ttl time.Duration) error {
+	t[key] = ttl
+	return nil
+}
+
+func (s *BoltSuite) TestPreloadCapsTTL() {
+	ctx := context.Background()
+
+	b := s.open()
+	defer b.Close()
+	s.NoError(b.Set(ctx, "long", []byte("1"), 30*24*time.Hour))
+	s.NoError(b.Set(ctx, "short", []byte("2"), time.Minute))
+
+	dst := ttlStore{}
+	n, err := b.Preload(ctx, dst, 10, 24*time.Hour)
+
+	s.NoError(err)
+	s.Equal(2, n)
+	s.Equal(24*time.Hour, dst["long"])
+	s.LessOrEqual(dst["short"], time.Minute)
+	s.Positive(dst["short"])
+}
+
+func (s *BoltSuite) TestAddressGetterSurvivesRestart() {
+	next := &mockAddressGetter{address: domain.Address{PostalCode: "01001000", City: "São Paulo"}}
+
+	b := s.open()
+	ag := cache.NewAddressGetter(next, b, time.Hour, time.Minute)
+	_, err := ag.GetAddress(context.Background(), "01001-000")
+	s.NoError(err)
+	s.NoError(b.Close())
+
+	b = s.open()
+	defer b.Close()
+	ag = cache.NewAddressGetter(next, b, time.Hour, time.Minute)
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+	s.Equal(1, next.calls)
+}
@@ -0,0 +1,277 @@
+// Package cache decorates [domain.AddressGetter] and
+// [domain.TemperatureGetter] with a cache kept in a [Store].
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// entry is what is kept in the store for every cached value. NotFound marks
+// a negative entry, for which Value is empty.
+type entry[T any] struct {
+	Value    T         `json:"value"`
+	NotFound bool      `json:"not_found,omitempty"`
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// AddressGetter caches the addresses returned by another
+// [domain.AddressGetter], keyed by normalized CEP.
+type AddressGetter struct {
+	next         domain.AddressGetter
+	store        Store
+	ttl          time.Duration
+	notFoundTTL  time.Duration
+	negativeHits metric.Int64Counter
+}
+
+// NewAddressGetter returns a [domain.AddressGetter] that keeps the addresses
+// found by next in store for ttl. CEPs for which next returns
+// [domain.ErrPostalCodeNotFound] are remembered for notFoundTTL, usually much
+// shorter; a zero notFoundTTL disables negative caching. Any other error is
+// never cached.
+func NewAddressGetter(next domain.AddressGetter, store Store, ttl, notFoundTTL time.Duration) domain.AddressGetter {
+	negativeHits, _ := otel.Meter("service-b").Int64Counter(
+		"cache.negative_hits",
+		metric.WithDescription("Lookups answered by a cached not found outcome"),
+	)
+
+	return &AddressGetter{
+		next:         next,
+		store:        store,
+		ttl:          ttl,
+		notFoundTTL:  notFoundTTL,
+		negativeHits: negativeHits,
+	}
+}
+
+// GetAddress implements [domain.AddressGetter].
+func (c *AddressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "cache-get-address")
+	defer span.End()
+
+	key := "address:" + domain.NormalizePostalCode(postalCode)
+
+	if e, ok := lookup[domain.Address](ctx, c.store, key); ok {
+		if e.NotFound {
+			span.SetAttributes(attribute.Bool("cache.negative", true))
+			c.negativeHits.Add(ctx, 1, metric.WithAttributes(attribute.String("cache", "address")))
+			return domain.Address{}, domain.ErrPostalCodeNotFound
+		}
+		return e.Value, nil
+	}
+
+	addr, err := c.next.GetAddress(ctx, postalCode)
+	if errors.Is(err, domain.ErrPostalCodeNotFound) && c.notFoundTTL > 0 {
+		saveEntry(ctx, c.store, key, entry[domain.Address]{NotFound: true}, c.notFoundTTL)
+		return domain.Address{}, err
+	}
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	save(ctx, c.store, key, addr, c.ttl)
+
+	return addr, nil
+}
+
+// Freshness configures how long cached observations are served.
+//
+// An observation younger than TTL is fresh. Up to StaleWhileRevalidate past
+// TTL it is served as stale while a refresh runs in the background. Up to
+// StaleIfError past TTL it is served as stale when the provider fails.
+type Freshness struct {
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+	StaleIfError         time.Duration
+}
+
+// storeTTL is how long an entry must be kept to honour every window.
+func (f Freshness) storeTTL() time.Duration {
+	return f.TTL + max(f.StaleWhileRevalidate, f.StaleIfError)
+}
+
+// refreshTimeout bounds background revalidations, which are not tied to any
+// request deadline.
+const refreshTimeout = 30 * time.Second
+
+// cache.state span attribute values.
+const (
+	stateMiss              = "miss"
+	stateFresh             = "fresh"
+	stateStaleRevalidating = "stale-revalidating"
+	stateStaleOnError      = "stale-on-error"
+)
+
+// TemperatureGetter caches the observations returned by another
+// [domain.TemperatureGetter], keyed by location.
+type TemperatureGetter struct {
+	next       domain.ObservationGetter
+	store      Store
+	freshness  Freshness
+	refreshing sync.Map
+}
+
+// NewTemperatureGetter returns a [domain.TemperatureGetter] that keeps the
+// observations made by next in store according to freshness. The returned
+// value also implements [domain.ObservationGetter].
+func NewTemperatureGetter(next domain.TemperatureGetter, store Store, freshness Freshness) domain.TemperatureGetter {
+	return &TemperatureGetter{next: domain.AsObservationGetter(next), store: store, freshness: freshness}
+}
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (c *TemperatureGetter) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := c.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter]. Stale observations
+// have [domain.Observation.Stale] set and their age in
+// [domain.Observation.Age]. Freshness is judged by how long the observation
+// has been cached, while its age counts from when the provider made it. The
+// cache state is recorded both in the cache
+// span and in the caller's span.
+func (c *TemperatureGetter) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	parent := trace.SpanFromContext(ctx)
+
+	ctx, span := otel.Tracer("service-b").Start(ctx, "cache-get-observation")
+	defer span.End()
+
+	setState := func(state string) {
+		span.SetAttributes(attribute.String("cache.state", state))
+		parent.SetAttributes(attribute.String("cache.state", state))
+	}
+
+	key := "observation:" + location.Key()
+
+	e, ok := lookup[domain.Observation](ctx, c.store, key)
+	if !ok {
+		setState(stateMiss)
+		return c.fetch(ctx, key, location)
+	}
+
+	age := time.Since(e.StoredAt)
+	span.SetAttributes(attribute.Float64("cache.age_seconds", age.Seconds()))
+
+	if age <= c.freshness.TTL {
+		setState(stateFresh)
+		return e.Value, nil
+	}
+
+	if age <= c.freshness.TTL+c.freshness.StaleWhileRevalidate {
+		setState(stateStaleRevalidating)
+		c.revalidate(ctx, key, location)
+		return stale(e.Value, age), nil
+	}
+
+	obs, err := c.fetch(ctx, key, location)
+	if err != nil && age <= c.freshness.TTL+c.freshness.StaleIfError {
+		span.RecordError(err)
+		setState(stateStaleOnError)
+		return stale(e.Value, age), nil
+	}
+
+	setState(stateMiss)
+	return obs, err
+}
+
+func (c *TemperatureGetter) fetch(ctx context.Context, key string, location domain.Location) (domain.Observation, error) {
+	obs, err := c.next.GetObservation(ctx, location)
+	if err != nil {
+		return domain.Observation{}, err
+	}
+
+	save(ctx, c.store, key, obs, c.freshness.storeTTL())
+
+	return obs, nil
+}
+
+// revalidate refreshes key in the background, unless a refresh for it is
+// already running. The refresh span is linked to the request that triggered
+// it.
+func (c *TemperatureGetter) revalidate(ctx context.Context, key string, location domain.Location) {
+	if _, running := c.refreshing.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+
+	link := trace.Link{SpanContext: trace.SpanContextFromContext(ctx)}
+
+	go func() {
+		defer c.refreshing.Delete(key)
+
+		ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+		defer cancel()
+
+		ctx, span := otel.Tracer("service-b").Start(ctx, "cache-revalidate-observation", trace.WithLinks(link))
+		defer span.End()
+
+		if _, err := c.fetch(ctx, key, location); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}()
+}
+
+// stale marks obs, cached for cachedFor, as stale. Its age is taken from its
+// observation time, unless the provider reported none, but is never less
+// than cachedFor, in case the clock of the provider is ahead of ours.
+func stale(obs domain.Observation, cachedFor time.Duration) domain.Observation {
+	obs.Stale = true
+	obs.Age = cachedFor
+	if !obs.ObservedAt.IsZero() {
+		obs.Age = max(time.Since(obs.ObservedAt), cachedFor)
+	}
+	return obs
+}
+
+// lookup reads key from store, recording the outcome in the current span.
+// Store failures are treated as misses, so a broken cache never fails a
+// request.
+func lookup[T any](ctx context.Context, store Store, key string) (entry[T], bool) {
+	span := trace.SpanFromContext(ctx)
+
+	var e entry[T]
+
+	b, err := store.Get(ctx, key)
+	if err == nil {
+		err = json.Unmarshal(b, &e)
+	}
+	if err != nil && !errors.Is(err, ErrMiss) {
+		span.RecordError(err)
+	}
+
+	span.SetAttributes(attribute.String("cache.key", key), attribute.Bool("cache.hit", err == nil))
+
+	return e, err == nil
+}
+
+// save writes value to store under key. Failures are only recorded.
+func save[T any](ctx context.Context, store Store, key string, value T, ttl time.Duration) {
+	saveEntry(ctx, store, key, entry[T]{Value: value}, ttl)
+}
+
+func saveEntry[T any](ctx context.Context, store Store, key string, e entry[T], ttl time.Duration) {
+	e.StoredAt = time.Now().UTC()
+
+	b, err := json.Marshal(e)
+	if err == nil {
+		err = store.Set(ctx, key, b, ttl)
+	}
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
@@ -0,0 +1,367 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/cache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type mockAddressGetter struct {
+	address domain.Address
+	err     error
+	calls   int
+}
+
+func (m *mockAddressGetter) GetAddress(_ context.Context, _ string) (domain.Address, error) {
+	m.calls++
+	return m.address, m.err
+}
+
+// mockObservationGetter is safe for concurrent use, since stale entries are
+// refreshed in the background.
+type mockObservationGetter struct {
+	mu    sync.Mutex
+	obs   domain.Observation
+	err   error
+	calls int
+}
+
+func (m *mockObservationGetter) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := m.GetObservation(ctx, location)
+	return obs.TempC, err
+}
+
+func (m *mockObservationGetter) GetObservation(_ context.Context, _ domain.Location) (domain.Observation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	return m.obs, m.err
+}
+
+func (m *mockObservationGetter) set(obs domain.Observation, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.obs, m.err = obs, err
+}
+
+func (m *mockObservationGetter) callCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.calls
+}
+
+type failingStore struct{}
+
+func (failingStore) Get(context.Context, string) ([]byte, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func (failingStore) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("store unavailable")
+}
+
+type CacheSuite struct {
+	suite.Suite
+}
+
+func TestCacheSuite(t *testing.T) {
+	suite.Run(t, new(CacheSuite))
+}
+
+func (s *CacheSuite) TestAddressHit() {
+	next := &mockAddressGetter{address: domain.Address{PostalCode: "01001000", City: "São Paulo"}}
+	ag := cache.NewAddressGetter(next, cache.NewLRU(10), time.Minute, 0)
+
+	first, err := ag.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+
+	// formatting differences share the same entry
+	second, err := ag.GetAddress(context.Background(), "01001-000")
+	s.NoError(err)
+
+	s.Equal(first, second)
+	s.Equal(1, next.calls)
+}
+
+func (s *CacheSuite) TestAddressExpired() {
+	next := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+	ag := cache.NewAddressGetter(next, cache.NewLRU(10), time.Millisecond, 0)
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = ag.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+
+	s.Equal(2, next.calls)
+}
+
+func (s *CacheSuite) TestAddressErrorNotCached() {
+	next := &mockAddressGetter{err: errors.New("service unavailable")}
+	ag := cache.NewAddressGetter(next, cache.NewLRU(10), time.Minute, 0)
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+	s.Error(err)
+
+	next.err = nil
+	next.address = domain.Address{City: "São Paulo"}
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+	s.Equal(2, next.calls)
+}
+
+func (s *CacheSuite) TestFailingStoreFallsThrough() {
+	next := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+	ag := cache.NewAddressGetter(next, failingStore{}, time.Minute, 0)
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+}
+
+func (s *CacheSuite) TestObservationHit() {
+	next := &mockObservationGetter{obs: domain.Observation{TempC: 25, Humidity: 60, Source: "wttr"}}
+	tg := cache.NewTemperatureGetter(next, cache.NewLRU(10), cache.Freshness{TTL: time.Minute})
+	og := tg.(domain.ObservationGetter)
+	loc := domain.Location{City: "São Paulo", UF: "SP"}
+
+	first, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+
+	temp, err := tg.GetTemperature(context.Background(), loc)
+	s.NoError(err)
+
+	s.Equal(25.0, temp)
+	s.Equal(60.0, first.Humidity)
+	s.Equal(1, next.callCount())
+}
+
+func (s *CacheSuite) TestObservationKeyedByLocation() {
+	next := &mockObservationGetter{obs: domain.Observation{TempC: 25}}
+	tg := cache.NewTemperatureGetter(next, cache.NewLRU(10), cache.Freshness{TTL: time.Minute})
+
+	_, err := tg.GetTemperature(context.Background(), domain.Location{City: "Bom Jesus", UF: "PI"})
+	s.NoError(err)
+
+	_, err = tg.GetTemperature(context.Background(), domain.Location{City: "Bom Jesus", UF: "RS"})
+	s.NoError(err)
+
+	_, err = tg.GetTemperature(context.Background(), domain.Location{
+		City:        "Bom Jesus",
+		UF:          "RS",
+		Coordinates: &domain.Coordinates{Latitude: -28.67, Longitude: -50.43},
+	})
+	s.NoError(err)
+
+	s.Equal(3, next.callCount())
+}
+
+func (s *CacheSuite) TestNotFoundCached() {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(noop.NewMeterProvider())
+
+	next := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	ag := cache.NewAddressGetter(next, cache.NewLRU(10), time.Hour, time.Minute)
+
+	for range 3 {
+		_, err := ag.GetAddress(context.Background(), "99999999")
+		s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	}
+
+	s.Equal(1, next.calls)
+
+	var rm metricdata.ResourceMetrics
+	s.Require().NoError(reader.Collect(context.Background(), &rm))
+	s.Equal(int64(2), s.counterValue(rm, "cache.negative_hits"))
+}
+
+func (s *CacheSuite) TestNotFoundExpiresSooner() {
+	next := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	ag := cache.NewAddressGetter(next, cache.NewLRU(10), time.Hour, time.Millisecond)
+
+	_, err := ag.GetAddress(context.Background(), "99999999")
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+
+	time.Sleep(5 * time.Millisecond)
+
+	next.err = nil
+	next.address = domain.Address{City: "São Paulo"}
+
+	addr, err := ag.GetAddress(context.Background(), "99999999")
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+	s.Equal(2, next.calls)
+}
+
+func (s *CacheSuite) TestNotFoundNotCachedWhenDisabled() {
+	next := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	ag := cache.NewAddressGetter(next, cache.NewLRU(10), time.Hour, 0)
+
+	for range 2 {
+		_, err := ag.GetAddress(context.Background(), "99999999")
+		s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	}
+
+	s.Equal(2, next.calls)
+}
+
+func (s *CacheSuite) TestTransientErrorNotCachedAsNotFound() {
+	next := &mockAddressGetter{err: errors.New("service unavailable")}
+	ag := cache.NewAddressGetter(next, cache.NewLRU(10), time.Hour, time.Minute)
+
+	for range 2 {
+		_, err := ag.GetAddress(context.Background(), "01001000")
+		s.Error(err)
+		s.NotErrorIs(err, domain.ErrPostalCodeNotFound)
+	}
+
+	s.Equal(2, next.calls)
+}
+
+func (s *CacheSuite) counterValue(rm metricdata.ResourceMetrics, name string) int64 {
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			s.Require().True(ok)
+
+			var total int64
+			for _, dp := range sum.DataPoints {
+				total += dp.Value
+			}
+			return total
+		}
+	}
+	return 0
+}
+
+func (s *CacheSuite) TestStaleWhileRevalidate() {
+	next := &mockObservationGetter{obs: domain.Observation{TempC: 25}}
+	og := cache.NewTemperatureGetter(next, cache.NewLRU(10), cache.Freshness{
+		TTL:                  10 * time.Millisecond,
+		StaleWhileRevalidate: time.Minute,
+	}).(domain.ObservationGetter)
+	loc := domain.Location{City: "São Paulo", UF: "SP"}
+
+	obs, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+	s.False(obs.Stale)
+
+	time.Sleep(20 * time.Millisecond)
+	next.set(domain.Observation{TempC: 26}, nil)
+
+	obs, err = og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+	s.True(obs.Stale)
+	s.GreaterOrEqual(obs.Age, 10*time.Millisecond)
+	s.Equal(25.0, obs.TempC)
+
+	s.Eventually(func() bool { return next.callCount() == 2 }, time.Second, time.Millisecond)
+
+	s.Eventually(func() bool {
+		obs, err := og.GetObservation(context.Background(), loc)
+		return err == nil && !obs.Stale && obs.TempC == 26
+	}, time.Second, time.Millisecond)
+}
+
+func (s *CacheSuite) TestStaleIfError() {
+	next := &mockObservationGetter{obs: domain.Observation{TempC: 25}}
+	og := cache.NewTemperatureGetter(next, cache.NewLRU(10), cache.Freshness{
+		TTL:          10 * time.Millisecond,
+		StaleIfError: time.Minute,
+	}).(domain.ObservationGetter)
+	loc := domain.Location{City: "São Paulo", UF: "SP"}
+
+	_, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+
+	time.Sleep(20 * time.Millisecond)
+	next.set(domain.Observation{}, errors.New("service unavailable"))
+
+	obs, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+	s.True(obs.Stale)
+	s.Equal(25.0, obs.TempC)
+	s.Equal(2, next.callCount())
+}
+
+func (s *CacheSuite) TestStaleAgeCountsFromObservation() {
+	observedAt := time.Now().Add(-time.Hour)
+	next := &mockObservationGetter{obs: domain.Observation{TempC: 25, ObservedAt: observedAt}}
+	og := cache.NewTemperatureGetter(next, cache.NewLRU(10), cache.Freshness{
+		TTL:          10 * time.Millisecond,
+		StaleIfError: time.Minute,
+	}).(domain.ObservationGetter)
+	loc := domain.Location{City: "São Paulo", UF: "SP"}
+
+	_, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+
+	time.Sleep(20 * time.Millisecond)
+	next.set(domain.Observation{}, errors.New("service unavailable"))
+
+	obs, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+	s.True(obs.Stale)
+	s.InDelta(time.Hour, obs.Age, float64(time.Second))
+}
+
+func (s *CacheSuite) TestStaleIfErrorRefreshesOnSuccess() {
+	next := &mockObservationGetter{obs: domain.Observation{TempC: 25}}
+	og := cache.NewTemperatureGetter(next, cache.NewLRU(10), cache.Freshness{
+		TTL:          10 * time.Millisecond,
+		StaleIfError: time.Minute,
+	}).(domain.ObservationGetter)
+	loc := domain.Location{City: "São Paulo", UF: "SP"}
+
+	_, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+
+	time.Sleep(20 * time.Millisecond)
+	next.set(domain.Observation{TempC: 26}, nil)
+
+	obs, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+	s.False(obs.Stale)
+	s.Equal(26.0, obs.TempC)
+}
+
+func (s *CacheSuite) TestTooStaleReturnsError() {
+	next := &mockObservationGetter{obs: domain.Observation{TempC: 25}}
+	og := cache.NewTemperatureGetter(next, cache.NewLRU(10), cache.Freshness{
+		TTL:          5 * time.Millisecond,
+		StaleIfError: 5 * time.Millisecond,
+	}).(domain.ObservationGetter)
+	loc := domain.Location{City: "São Paulo", UF: "SP"}
+
+	_, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+
+	time.Sleep(20 * time.Millisecond)
+	expectedErr := errors.New("service unavailable")
+	next.set(domain.Observation{}, expectedErr)
+
+	_, err = og.GetObservation(context.Background(), loc)
+	s.ErrorIs(err, expectedErr)
+}
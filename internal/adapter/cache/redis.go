@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a [Store] kept in a server speaking the Redis protocol, so that
+// every replica sharing the server shares the cache.
+//
+// Keys are prefixed with a version: changing it makes every replica ignore
+// the entries written under the previous one, which then simply expire.
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedis returns a [Redis] store using client, whose keys are prefixed
+// with "<namespace>:<version>:".
+func NewRedis(client redis.UniversalClient, namespace, version string) *Redis {
+	return &Redis{client: client, prefix: namespace + ":" + version + ":"}
+}
+
+// Get implements [Store].
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Set implements [Store].
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
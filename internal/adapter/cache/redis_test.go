@@ -0,0 +1,130 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/cache"
+)
+
+type RedisSuite struct {
+	suite.Suite
+	server *miniredis.Miniredis
+	client *redis.Client
+}
+
+func TestRedisSuite(t *testing.T) {
+	suite.Run(t, new(RedisSuite))
+}
+
+func (s *RedisSuite) SetupTest() {
+	s.server = miniredis.RunT(s.T())
+	s.client = redis.NewClient(&redis.Options{Addr: s.server.Addr()})
+}
+
+func (s *RedisSuite) TearDownTest() {
+	s.client.Close()
+}
+
+func (s *RedisSuite) TestGetMissing() {
+	r := cache.NewRedis(s.client, "weather", "v1")
+
+	v, err := r.Get(context.Background(), "a")
+
+	s.ErrorIs(err, cache.ErrMiss)
+	s.Nil(v)
+}
+
+func (s *RedisSuite) TestSetAndGet() {
+	r := cache.NewRedis(s.client, "weather", "v1")
+
+	s.NoError(r.Set(context.Background(), "a", []byte("1"), time.Minute))
+	v, err := r.Get(context.Background(), "a")
+
+	s.NoError(err)
+	s.Equal([]byte("1"), v)
+	s.True(s.server.Exists("weather:v1:a"))
+	s.Equal(time.Minute, s.server.TTL("weather:v1:a"))
+}
+
+func (s *RedisSuite) TestExpired() {
+	r := cache.NewRedis(s.client, "weather", "v1")
+
+	s.NoError(r.Set(context.Background(), "a", []byte("1"), time.Minute))
+	s.server.FastForward(2 * time.Minute)
+	v, err := r.Get(context.Background(), "a")
+
+	s.ErrorIs(err, cache.ErrMiss)
+	s.Nil(v)
+}
+
+func (s *RedisSuite) TestVersionInvalidates() {
+	s.NoError(cache.NewRedis(s.client, "weather", "v1").Set(context.Background(), "a", []byte("1"), time.Minute))
+
+	_, err := cache.NewRedis(s.client, "weather", "v2").Get(context.Background(), "a")
+
+	s.ErrorIs(err, cache.ErrMiss)
+}
+
+func (s *RedisSuite) TestServerDown() {
+	r := cache.NewRedis(s.client, "weather", "v1")
+	s.server.Close()
+
+	_, err := r.Get(context.Background(), "a")
+
+	s.Error(err)
+	s.NotErrorIs(err, cache.ErrMiss)
+}
+
+func (s *RedisSuite) TestSharedBetweenReplicas() {
+	next := &mockAddressGetter{address: domain.Address{PostalCode: "01001000", City: "São Paulo"}}
+	other := redis.NewClient(&redis.Options{Addr: s.server.Addr()})
+	defer other.Close()
+
+	a := cache.NewAddressGetter(next, cache.NewRedis(s.client, "weather", "v1"), time.Hour, time.Minute)
+	b := cache.NewAddressGetter(next, cache.NewRedis(other, "weather", "v1"), time.Hour, time.Minute)
+
+	_, err := a.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+	addr, err := b.GetAddress(context.Background(), "01001-000")
+
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+	s.Equal(1, next.calls)
+}
+
+func (s *RedisSuite) TestNegativeEntry() {
+	next := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	ag := cache.NewAddressGetter(next, cache.NewRedis(s.client, "weather", "v1"), time.Hour, time.Minute)
+
+	_, err := ag.GetAddress(context.Background(), "99999999")
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Equal(time.Minute, s.server.TTL("weather:v1:address:99999999"))
+
+	_, err = ag.GetAddress(context.Background(), "99999999")
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Equal(1, next.calls)
+}
+
+func (s *RedisSuite) TestObservation() {
+	next := &mockObservationGetter{obs: domain.Observation{TempC: 25}}
+	og := cache.NewTemperatureGetter(next, cache.NewRedis(s.client, "weather", "v1"), cache.Freshness{
+		TTL:          time.Minute,
+		StaleIfError: time.Hour,
+	}).(domain.ObservationGetter)
+	loc := domain.Location{City: "São Paulo", UF: "SP"}
+
+	_, err := og.GetObservation(context.Background(), loc)
+	s.NoError(err)
+	obs, err := og.GetObservation(context.Background(), loc)
+
+	s.NoError(err)
+	s.Equal(25.0, obs.TempC)
+	s.Equal(1, next.callCount())
+	s.Equal(time.Hour+time.Minute, s.server.TTL("weather:v1:observation:São Paulo/SP"))
+}
@@ -0,0 +1,87 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/cache"
+)
+
+type LRUSuite struct {
+	suite.Suite
+}
+
+func TestLRUSuite(t *testing.T) {
+	suite.Run(t, new(LRUSuite))
+}
+
+func (s *LRUSuite) TestGetMissing() {
+	lru := cache.NewLRU(2)
+
+	v, err := lru.Get(context.Background(), "a")
+
+	s.ErrorIs(err, cache.ErrMiss)
+	s.Nil(v)
+}
+
+func (s *LRUSuite) TestSetAndGet() {
+	lru := cache.NewLRU(2)
+
+	s.NoError(lru.Set(context.Background(), "a", []byte("1"), time.Minute))
+	v, err := lru.Get(context.Background(), "a")
+
+	s.NoError(err)
+	s.Equal([]byte("1"), v)
+}
+
+func (s *LRUSuite) TestExpired() {
+	lru := cache.NewLRU(2)
+
+	s.NoError(lru.Set(context.Background(), "a", []byte("1"), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	v, err := lru.Get(context.Background(), "a")
+
+	s.ErrorIs(err, cache.ErrMiss)
+	s.Nil(v)
+	s.Zero(lru.Len())
+}
+
+func (s *LRUSuite) TestEvictsLeastRecentlyUsed() {
+	lru := cache.NewLRU(2)
+	ctx := context.Background()
+
+	s.NoError(lru.Set(ctx, "a", []byte("1"), time.Minute))
+	s.NoError(lru.Set(ctx, "b", []byte("2"), time.Minute))
+
+	// "a" becomes the most recently used, so "b" is the one evicted
+	_, err := lru.Get(ctx, "a")
+	s.NoError(err)
+
+	s.NoError(lru.Set(ctx, "c", []byte("3"), time.Minute))
+
+	s.Equal(2, lru.Len())
+
+	_, err = lru.Get(ctx, "b")
+	s.ErrorIs(err, cache.ErrMiss)
+
+	_, err = lru.Get(ctx, "a")
+	s.NoError(err)
+
+	_, err = lru.Get(ctx, "c")
+	s.NoError(err)
+}
+
+func (s *LRUSuite) TestOverwrite() {
+	lru := cache.NewLRU(2)
+	ctx := context.Background()
+
+	s.NoError(lru.Set(ctx, "a", []byte("1"), time.Minute))
+	s.NoError(lru.Set(ctx, "a", []byte("2"), time.Minute))
+	v, err := lru.Get(ctx, "a")
+
+	s.NoError(err)
+	s.Equal([]byte("2"), v)
+	s.Equal(1, lru.Len())
+}
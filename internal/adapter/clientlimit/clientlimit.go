@@ -0,0 +1,256 @@
+// Package clientlimit limits how often each client may call a service, with
+// a token bucket per client. Clients are identified by their API key or,
+// without one, by their IP address, and get the limits of their tier.
+package clientlimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+)
+
+// APIKeyHeader is the header carrying the API key of a client.
+const APIKeyHeader = "X-API-Key"
+
+// idleTTL is how long the bucket of a client is kept after its last request.
+// A bucket idle for longer is full anyway, so forgetting it changes nothing
+// as long as idleTTL exceeds the time any tier takes to refill.
+const idleTTL = 10 * time.Minute
+
+// Tier is the limit shared by a class of clients: Rate requests per second
+// on average, in bursts of at most Burst requests.
+type Tier struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Policy is the table of client limits.
+type Policy struct {
+	// Default is the tier of clients without a known API key, limited by IP.
+	Default Tier `json:"default"`
+	// Tiers are the tiers API keys may belong to, by name.
+	Tiers map[string]Tier `json:"tiers"`
+	// Keys maps API keys to the name of their tier.
+	Keys map[string]string `json:"keys"`
+	// TrustedProxies are the networks of the proxies whose X-Forwarded-For
+	// header is trusted to carry the client IP.
+	TrustedProxies []netip.Prefix `json:"trusted_proxies"`
+}
+
+// DefaultPolicy is the [Policy] used when none is configured.
+var DefaultPolicy = Policy{Default: Tier{Rate: 5, Burst: 10}}
+
+// LoadPolicy reads a [Policy] from the JSON file at path.
+func LoadPolicy(path string) (Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("reading policy: %w", err)
+	}
+
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return Policy{}, fmt.Errorf("decoding policy: %w", err)
+	}
+
+	return p, p.validate()
+}
+
+func (p Policy) validate() error {
+	if p.Default.Rate <= 0 || p.Default.Burst <= 0 {
+		return fmt.Errorf("default tier needs a positive rate and burst")
+	}
+	for name, t := range p.Tiers {
+		if t.Rate <= 0 || t.Burst <= 0 {
+			return fmt.Errorf("tier %q needs a positive rate and burst", name)
+		}
+	}
+	for _, name := range p.Keys {
+		if _, ok := p.Tiers[name]; !ok {
+			return fmt.Errorf("unknown tier %q", name)
+		}
+	}
+	return nil
+}
+
+// Limiter keeps the buckets of every client. It is safe for concurrent use.
+type Limiter struct {
+	policy   Policy
+	rejected metric.Int64Counter
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	tier     Tier
+	lastSeen time.Time
+}
+
+// New returns a [Limiter] enforcing policy.
+func New(policy Policy) *Limiter {
+	rejected, _ := otel.Meter("service-a").Int64Counter(
+		"clientlimit.rejected",
+		metric.WithDescription("Requests rejected for exceeding the client limit"),
+	)
+
+	return &Limiter{
+		policy:    policy,
+		rejected:  rejected,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Middleware returns a gin middleware answering 429 Too Many Requests, with
+// a Retry-After header, to clients over their limit. Every response carries
+// the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and
+// RateLimit-Policy headers.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		client, tierName, tier := l.identify(ctx.Request)
+
+		trace.SpanFromContext(ctx.Request.Context()).SetAttributes(
+			attribute.String("client.tier", tierName),
+		)
+
+		allowed, remaining, reset, retryAfter := l.take(client, tier)
+
+		h := ctx.Writer.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(tier.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		h.Set("RateLimit-Reset", seconds(reset))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", tier.Burst, seconds(window(tier))))
+
+		if !allowed {
+			l.rejected.Add(ctx.Request.Context(), 1, metric.WithAttributes(attribute.String("tier", tierName)))
+			h.Set("Retry-After", seconds(retryAfter))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// identify returns the key of the bucket of the client of r and its tier.
+func (l *Limiter) identify(r *http.Request) (string, string, Tier) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		if name, ok := l.policy.Keys[key]; ok {
+			return "key:" + key, name, l.policy.Tiers[name]
+		}
+	}
+
+	return "ip:" + l.clientIP(r), "default", l.policy.Default
+}
+
+// clientIP returns the address the request came from. When it came through
+// trusted proxies, X-Forwarded-For is read from the right, skipping the
+// trusted addresses, since everything left of the first untrusted one may
+// have been forged by the client.
+func (l *Limiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !l.trusted(addr) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !l.trusted(addr) {
+			break
+		}
+	}
+
+	return addr.String()
+}
+
+func (l *Limiter) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range l.policy.TrustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// take takes a token from the bucket of client. Besides whether it was
+// allowed, it returns how many tokens are left, how long until the bucket
+// is full again and, when not allowed, how long until a token is available.
+func (l *Limiter) take(client string, tier Tier) (bool, int, time.Duration, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[client]
+	if !ok || b.tier != tier {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(tier.Rate), tier.Burst), tier: tier}
+		l.buckets[client] = b
+	}
+	b.lastSeen = now
+
+	allowed := b.limiter.AllowN(now, 1)
+	tokens := b.limiter.TokensAt(now)
+
+	var retryAfter time.Duration
+	if !allowed {
+		retryAfter = time.Duration((1 - tokens) / tier.Rate * float64(time.Second))
+	}
+	reset := time.Duration((float64(tier.Burst) - tokens) / tier.Rate * float64(time.Second))
+
+	return allowed, max(0, int(math.Floor(tokens))), reset, retryAfter
+}
+
+// sweep forgets the buckets idle for longer than idleTTL, at most once per
+// idleTTL.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for k, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTTL {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// window is how long an empty bucket of tier takes to fill up.
+func window(tier Tier) time.Duration {
+	return time.Duration(float64(tier.Burst) / tier.Rate * float64(time.Second))
+}
+
+// seconds formats d as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(max(0, int(math.Ceil(d.Seconds()))))
+}
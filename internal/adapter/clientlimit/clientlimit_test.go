@@ -0,0 +1,178 @@
+package clientlimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/clientlimit"
+)
+
+type ClientLimitSuite struct {
+	suite.Suite
+	policy clientlimit.Policy
+}
+
+func TestClientLimitSuite(t *testing.T) {
+	suite.Run(t, new(ClientLimitSuite))
+}
+
+func (s *ClientLimitSuite) SetupTest() {
+	s.policy = clientlimit.Policy{
+		Default: clientlimit.Tier{Rate: 0.001, Burst: 2},
+		Tiers:   map[string]clientlimit.Tier{"gold": {Rate: 0.001, Burst: 5}},
+		Keys:    map[string]string{"gold-key": "gold"},
+		TrustedProxies: []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+		},
+	}
+}
+
+func (s *ClientLimitSuite) handler() http.Handler {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.Use(clientlimit.New(s.policy).Middleware())
+	e.POST("/temperature", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	return e
+}
+
+func (s *ClientLimitSuite) do(h http.Handler, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/temperature", nil)
+	req.RemoteAddr = remoteAddr
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func (s *ClientLimitSuite) TestLimitsByIP() {
+	h := s.handler()
+
+	w := s.do(h, "192.0.2.1:1234", nil)
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal("2", w.Header().Get("RateLimit-Limit"))
+	s.Equal("1", w.Header().Get("RateLimit-Remaining"))
+
+	s.Equal(http.StatusOK, s.do(h, "192.0.2.1:1234", nil).Code)
+
+	w = s.do(h, "192.0.2.1:4321", nil)
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	s.Equal("0", w.Header().Get("RateLimit-Remaining"))
+	s.JSONEq(`{"error":"rate limit exceeded"}`, w.Body.String())
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	s.Require().NoError(err)
+	s.Positive(retryAfter)
+
+	// other clients have their own buckets
+	s.Equal(http.StatusOK, s.do(h, "192.0.2.2:1234", nil).Code)
+}
+
+func (s *ClientLimitSuite) TestLimitsByAPIKey() {
+	h := s.handler()
+	header := http.Header{clientlimit.APIKeyHeader: {"gold-key"}}
+
+	for i := range 5 {
+		// the key is limited wherever it comes from
+		w := s.do(h, "192.0.2."+strconv.Itoa(i)+":1234", header)
+		s.Equal(http.StatusOK, w.Code)
+		s.Equal("5", w.Header().Get("RateLimit-Limit"))
+	}
+
+	s.Equal(http.StatusTooManyRequests, s.do(h, "192.0.2.1:1234", header).Code)
+
+	// the IP of the key holder still has its own bucket
+	s.Equal(http.StatusOK, s.do(h, "192.0.2.1:1234", nil).Code)
+}
+
+func (s *ClientLimitSuite) TestUnknownAPIKeyLimitedByIP() {
+	h := s.handler()
+
+	s.Equal(http.StatusOK, s.do(h, "192.0.2.1:1234", http.Header{clientlimit.APIKeyHeader: {"a"}}).Code)
+	s.Equal(http.StatusOK, s.do(h, "192.0.2.1:1234", http.Header{clientlimit.APIKeyHeader: {"b"}}).Code)
+
+	w := s.do(h, "192.0.2.1:1234", http.Header{clientlimit.APIKeyHeader: {"c"}})
+	s.Equal(http.StatusTooManyRequests, w.Code)
+	s.Equal("2", w.Header().Get("RateLimit-Limit"))
+}
+
+func (s *ClientLimitSuite) TestTrustedProxy() {
+	h := s.handler()
+
+	for range 2 {
+		header := http.Header{"X-Forwarded-For": {"203.0.113.9, 10.0.0.2"}}
+		s.Equal(http.StatusOK, s.do(h, "10.0.0.1:1234", header).Code)
+	}
+
+	// the client is limited whichever proxy it comes through
+	header := http.Header{"X-Forwarded-For": {"203.0.113.9"}}
+	s.Equal(http.StatusTooManyRequests, s.do(h, "10.0.0.3:1234", header).Code)
+
+	// but the proxy itself is not
+	s.Equal(http.StatusOK, s.do(h, "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"203.0.113.10"}}).Code)
+}
+
+func (s *ClientLimitSuite) TestForgedForwardedFor() {
+	h := s.handler()
+
+	// an untrusted peer can not pick its IP
+	for i := range 3 {
+		header := http.Header{"X-Forwarded-For": {"203.0.113." + strconv.Itoa(i)}}
+		w := s.do(h, "192.0.2.1:1234", header)
+		if i < 2 {
+			s.Equal(http.StatusOK, w.Code)
+		} else {
+			s.Equal(http.StatusTooManyRequests, w.Code)
+		}
+	}
+
+	// nor can a client behind a trusted proxy, by prepending addresses
+	for i := range 3 {
+		header := http.Header{"X-Forwarded-For": {"198.51.100." + strconv.Itoa(i) + ", 203.0.113.50"}}
+		w := s.do(h, "10.0.0.1:1234", header)
+		if i < 2 {
+			s.Equal(http.StatusOK, w.Code)
+		} else {
+			s.Equal(http.StatusTooManyRequests, w.Code)
+		}
+	}
+}
+
+func (s *ClientLimitSuite) TestLoadPolicy() {
+	path := filepath.Join(s.T().TempDir(), "policy.json")
+	s.Require().NoError(os.WriteFile(path, []byte(`{
+		"default": {"rate": 1, "burst": 2},
+		"tiers": {"gold": {"rate": 50, "burst": 100}},
+		"keys": {"abc": "gold"},
+		"trusted_proxies": ["10.0.0.0/8"]
+	}`), 0o600))
+
+	p, err := clientlimit.LoadPolicy(path)
+	s.Require().NoError(err)
+	s.Equal(clientlimit.Tier{Rate: 1, Burst: 2}, p.Default)
+	s.Equal(clientlimit.Tier{Rate: 50, Burst: 100}, p.Tiers["gold"])
+	s.Equal("gold", p.Keys["abc"])
+	s.Equal([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, p.TrustedProxies)
+}
+
+func (s *ClientLimitSuite) TestLoadPolicyUnknownTier() {
+	path := filepath.Join(s.T().TempDir(), "policy.json")
+	s.Require().NoError(os.WriteFile(path, []byte(`{
+		"default": {"rate": 1, "burst": 2},
+		"keys": {"abc": "gold"}
+	}`), 0o600))
+
+	_, err := clientlimit.LoadPolicy(path)
+	s.ErrorContains(err, `unknown tier "gold"`)
+}
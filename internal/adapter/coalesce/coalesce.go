@@ -0,0 +1,64 @@
+// Package coalesce decorates [domain.AddressGetter] and
+// [domain.TemperatureGetter] so that concurrent lookups of the same CEP or
+// location share a single upstream call.
+package coalesce
+
+import (
+	"context"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+)
+
+// AddressGetter coalesces concurrent lookups of the same CEP.
+type AddressGetter struct {
+	next  domain.AddressGetter
+	group *group[domain.Address]
+}
+
+// NewAddressGetter returns a [domain.AddressGetter] that shares in-flight
+// calls to next among callers asking for the same CEP.
+func NewAddressGetter(next domain.AddressGetter) domain.AddressGetter {
+	return &AddressGetter{next: next, group: newGroup[domain.Address]("coalesced-get-address")}
+}
+
+// GetAddress implements [domain.AddressGetter].
+func (c *AddressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	key := domain.NormalizePostalCode(postalCode)
+
+	return c.group.do(ctx, key, func(ctx context.Context) (domain.Address, error) {
+		return c.next.GetAddress(ctx, postalCode)
+	})
+}
+
+// TemperatureGetter coalesces concurrent lookups of the same location.
+type TemperatureGetter struct {
+	next  domain.ObservationGetter
+	group *group[domain.Observation]
+}
+
+// NewTemperatureGetter returns a [domain.TemperatureGetter] that shares
+// in-flight calls to next among callers asking for the same location. The
+// returned value also implements [domain.ObservationGetter].
+func NewTemperatureGetter(next domain.TemperatureGetter) domain.TemperatureGetter {
+	return &TemperatureGetter{
+		next:  domain.AsObservationGetter(next),
+		group: newGroup[domain.Observation]("coalesced-get-observation"),
+	}
+}
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (c *TemperatureGetter) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := c.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter].
+func (c *TemperatureGetter) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	return c.group.do(ctx, location.Key(), func(ctx context.Context) (domain.Observation, error) {
+		return c.next.GetObservation(ctx, location)
+	})
+}
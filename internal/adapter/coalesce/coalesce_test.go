@@ -0,0 +1,285 @@
+package coalesce_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/coalesce"
+)
+
+// blockingAddressGetter blocks every call until release is closed or the
+// call's context is cancelled.
+type blockingAddressGetter struct {
+	release   chan struct{}
+	calls     atomic.Int32
+	cancelled chan struct{}
+	cause     error
+	err       error
+}
+
+func newBlockingAddressGetter() *blockingAddressGetter {
+	return &blockingAddressGetter{release: make(chan struct{}), cancelled: make(chan struct{})}
+}
+
+func (m *blockingAddressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	m.calls.Add(1)
+	select {
+	case <-m.release:
+		return domain.Address{PostalCode: postalCode, City: "São Paulo"}, m.err
+	case <-ctx.Done():
+		m.cause = context.Cause(ctx)
+		close(m.cancelled)
+		return domain.Address{}, ctx.Err()
+	}
+}
+
+type addressGetterFunc func(ctx context.Context, postalCode string) (domain.Address, error)
+
+func (f addressGetterFunc) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	return f(ctx, postalCode)
+}
+
+type mockTemperatureGetter struct {
+	calls atomic.Int32
+}
+
+func (m *mockTemperatureGetter) GetTemperature(_ context.Context, _ domain.Location) (float64, error) {
+	m.calls.Add(1)
+	return 25, nil
+}
+
+type CoalesceSuite struct {
+	suite.Suite
+}
+
+func TestCoalesceSuite(t *testing.T) {
+	suite.Run(t, new(CoalesceSuite))
+}
+
+// waitJoined gives the goroutines started by the test time to join the
+// in-flight call.
+func (s *CoalesceSuite) waitJoined() {
+	time.Sleep(20 * time.Millisecond)
+}
+
+func (s *CoalesceSuite) TestConcurrentCallsShareUpstream() {
+	next := newBlockingAddressGetter()
+	ag := coalesce.NewAddressGetter(next)
+
+	var wg sync.WaitGroup
+	results := make([]domain.Address, 10)
+	for i := range results {
+		wg.Go(func() {
+			addr, err := ag.GetAddress(context.Background(), "01001000")
+			s.NoError(err)
+			results[i] = addr
+		})
+	}
+
+	s.waitJoined()
+	close(next.release)
+	wg.Wait()
+
+	s.Equal(int32(1), next.calls.Load())
+	for _, addr := range results {
+		s.Equal("São Paulo", addr.City)
+	}
+}
+
+func (s *CoalesceSuite) TestErrorIsFannedOut() {
+	next := newBlockingAddressGetter()
+	next.err = errors.New("service unavailable")
+	ag := coalesce.NewAddressGetter(next)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			_, err := ag.GetAddress(context.Background(), "01001000")
+			s.ErrorIs(err, next.err)
+		})
+	}
+
+	s.waitJoined()
+	close(next.release)
+	wg.Wait()
+
+	s.Equal(int32(1), next.calls.Load())
+}
+
+func (s *CoalesceSuite) TestSequentialCallsAreNotShared() {
+	next := newBlockingAddressGetter()
+	close(next.release)
+	ag := coalesce.NewAddressGetter(next)
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+	_, err = ag.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+
+	s.Equal(int32(2), next.calls.Load())
+}
+
+func (s *CoalesceSuite) TestOneWaiterLeavingDoesNotCancel() {
+	next := newBlockingAddressGetter()
+	ag := coalesce.NewAddressGetter(next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		_, err := ag.GetAddress(ctx, "01001000")
+		s.ErrorIs(err, context.Canceled)
+	})
+	wg.Go(func() {
+		addr, err := ag.GetAddress(context.Background(), "01001000")
+		s.NoError(err)
+		s.Equal("São Paulo", addr.City)
+	})
+
+	s.waitJoined()
+	cancel()
+	s.waitJoined()
+
+	select {
+	case <-next.cancelled:
+		s.Fail("upstream cancelled while a caller was still waiting")
+	default:
+	}
+
+	close(next.release)
+	wg.Wait()
+}
+
+func (s *CoalesceSuite) TestAllWaitersLeavingCancels() {
+	next := newBlockingAddressGetter()
+	ag := coalesce.NewAddressGetter(next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Go(func() {
+			_, err := ag.GetAddress(ctx, "01001000")
+			s.ErrorIs(err, context.Canceled)
+		})
+	}
+
+	s.waitJoined()
+	cancel()
+	wg.Wait()
+
+	select {
+	case <-next.cancelled:
+	case <-time.After(time.Second):
+		s.Fail("upstream not cancelled after every caller left")
+	}
+}
+
+func (s *CoalesceSuite) TestCancellationKeepsDeadlineCause() {
+	next := newBlockingAddressGetter()
+	ag := coalesce.NewAddressGetter(next)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	_, err := ag.GetAddress(ctx, "01001000")
+	s.ErrorIs(err, context.DeadlineExceeded)
+
+	select {
+	case <-next.cancelled:
+		s.ErrorIs(next.cause, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		s.Fail("upstream not cancelled after every caller left")
+	}
+}
+
+func (s *CoalesceSuite) TestCallKeepsLatestDeadline() {
+	var (
+		deadline time.Time
+		err      atomic.Value
+	)
+	ag := coalesce.NewAddressGetter(addressGetterFunc(func(ctx context.Context, _ string) (domain.Address, error) {
+		deadline, _ = ctx.Deadline()
+		<-ctx.Done()
+		err.Store(ctx.Err())
+		return domain.Address{}, ctx.Err()
+	}))
+
+	short, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	long, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		_, err := ag.GetAddress(short, "01001000")
+		s.ErrorIs(err, context.DeadlineExceeded)
+	})
+	s.waitJoined()
+	wg.Go(func() {
+		_, err := ag.GetAddress(long, "01001000")
+		s.ErrorIs(err, context.DeadlineExceeded)
+	})
+
+	// the call outlives the first caller, until the deadline of the second
+	time.Sleep(100 * time.Millisecond)
+	s.Nil(err.Load())
+
+	wg.Wait()
+	s.Eventually(func() bool { return err.Load() != nil }, time.Second, 5*time.Millisecond)
+	s.ErrorIs(err.Load().(error), context.DeadlineExceeded)
+
+	want, _ := short.Deadline()
+	s.Equal(want, deadline)
+}
+
+func (s *CoalesceSuite) TestCallWithoutDeadline() {
+	var hasDeadline atomic.Bool
+	ag := coalesce.NewAddressGetter(addressGetterFunc(func(ctx context.Context, postalCode string) (domain.Address, error) {
+		_, ok := ctx.Deadline()
+		hasDeadline.Store(ok)
+		return domain.Address{PostalCode: postalCode}, nil
+	}))
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.False(hasDeadline.Load())
+}
+
+func (s *CoalesceSuite) TestPanicFailsEveryCaller() {
+	release := make(chan struct{})
+	ag := coalesce.NewAddressGetter(addressGetterFunc(func(context.Context, string) (domain.Address, error) {
+		<-release
+		panic("boom")
+	}))
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Go(func() {
+			_, err := ag.GetAddress(context.Background(), "01001000")
+			s.ErrorContains(err, "coalesced call panicked: boom")
+		})
+	}
+
+	s.waitJoined()
+	close(release)
+	wg.Wait()
+}
+
+func (s *CoalesceSuite) TestTemperature() {
+	next := &mockTemperatureGetter{}
+	tg := coalesce.NewTemperatureGetter(next)
+
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo", UF: "SP"})
+
+	s.NoError(err)
+	s.Equal(25.0, temp)
+	s.Equal(int32(1), next.calls.Load())
+}
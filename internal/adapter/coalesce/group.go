@@ -0,0 +1,222 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// call is an upstream call shared by every caller asking for the same key
+// while it is in flight.
+type call[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	waiters int
+	ctx     *callContext
+	span    trace.Span
+}
+
+// group collapses concurrent calls for the same key into a single one. Unlike
+// golang.org/x/sync/singleflight, the shared call is only cancelled once every
+// caller waiting for it has gone away.
+type group[V any] struct {
+	name  string
+	mu    sync.Mutex
+	calls map[string]*call[V]
+}
+
+func newGroup[V any](name string) *group[V] {
+	return &group[V]{name: name, calls: make(map[string]*call[V])}
+}
+
+// do returns the result of fn for key, running fn only if no call for key is
+// already in flight. fn runs under the shared span, which is a child of the
+// first caller's span and linked from every other caller's span, and with
+// the latest deadline of the callers waiting for it.
+func (g *group[V]) do(ctx context.Context, key string, fn func(context.Context) (V, error)) (V, error) {
+	tracer := otel.Tracer("service-b")
+
+	ctx, span := tracer.Start(ctx, "coalesce-wait")
+	defer span.End()
+
+	g.mu.Lock()
+	c, shared := g.calls[key]
+	// a call past its deadline is about to fail, so it is not joined
+	if shared && c.ctx.Err() == nil {
+		c.waiters++
+		c.ctx.join(ctx)
+		c.span.AddLink(trace.Link{SpanContext: span.SpanContext()})
+	} else {
+		shared = false
+
+		cc := newCallContext(ctx)
+		callCtx, callSpan := tracer.Start(cc, g.name, trace.WithAttributes(attribute.String("coalesce.key", key)))
+
+		c = &call[V]{done: make(chan struct{}), waiters: 1, ctx: cc, span: callSpan}
+		g.calls[key] = c
+
+		go g.run(callCtx, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	span.AddLink(trace.Link{SpanContext: c.span.SpanContext()})
+	span.SetAttributes(attribute.Bool("coalesce.shared", shared))
+
+	select {
+	case <-c.done:
+		return c.val, c.err
+	case <-ctx.Done():
+		g.leave(key, c, context.Cause(ctx))
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (g *group[V]) run(ctx context.Context, key string, c *call[V], fn func(context.Context) (V, error)) {
+	defer c.span.End()
+	defer c.ctx.cancel(context.Canceled)
+
+	val, err := safeCall(ctx, fn)
+
+	g.mu.Lock()
+	c.val, c.err = val, err
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	c.span.SetAttributes(attribute.Int("coalesce.waiters", c.waiters))
+	g.mu.Unlock()
+
+	if err != nil {
+		c.span.RecordError(err)
+	}
+
+	close(c.done)
+}
+
+// safeCall calls fn, turning a panic into an error for every caller instead
+// of letting it crash the process from the goroutine of the shared call.
+func safeCall[V any](ctx context.Context, fn func(context.Context) (V, error)) (val V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("coalesced call panicked: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("coalesced call panicked: %v", r)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// leave unregisters a caller that stopped waiting, for the given cause,
+// cancelling the shared call with that cause when it was the last one, so
+// that the layers below can tell a deadline from a caller going away. The
+// call is also forgotten so that new callers start a fresh one instead of
+// joining a cancelled call.
+func (g *group[V]) leave(key string, c *call[V], cause error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	c.ctx.cancel(cause)
+}
+
+// callContext is the context of a shared call. It carries the values of the
+// first caller, but not its cancellation: it is only cancelled once every
+// caller left, or when its deadline, the latest of those of its callers,
+// passes. A caller without a deadline leaves the call without one.
+type callContext struct {
+	context.Context
+
+	done chan struct{}
+
+	mu        sync.Mutex
+	err       error
+	deadline  time.Time
+	unbounded bool
+	timer     *time.Timer
+}
+
+func newCallContext(ctx context.Context) *callContext {
+	c := &callContext{Context: context.WithoutCancel(ctx), done: make(chan struct{})}
+	c.join(ctx)
+	return c
+}
+
+// join moves the deadline of c to the deadline of ctx when it is later.
+func (c *callContext) join(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.unbounded, c.err != nil:
+	case !ok:
+		c.unbounded, c.deadline = true, time.Time{}
+		if c.timer != nil {
+			c.timer.Stop()
+		}
+	case c.timer == nil:
+		c.deadline = deadline
+		c.timer = time.AfterFunc(time.Until(deadline), func() { c.cancel(context.DeadlineExceeded) })
+	case deadline.After(c.deadline):
+		c.deadline = deadline
+		c.timer.Reset(time.Until(deadline))
+	}
+}
+
+// cancel ends c, with [context.DeadlineExceeded] when cause is a deadline
+// and [context.Canceled] otherwise. Only the first call has any effect.
+func (c *callContext) cancel(cause error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+
+	c.err = context.Canceled
+	if errors.Is(cause, context.DeadlineExceeded) {
+		c.err = context.DeadlineExceeded
+	}
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	close(c.done)
+}
+
+// Deadline implements [context.Context].
+func (c *callContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.deadline, !c.deadline.IsZero()
+}
+
+// Done implements [context.Context].
+func (c *callContext) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err implements [context.Context].
+func (c *callContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
@@ -0,0 +1,92 @@
+// Package deadline propagates the time budget of a request between services,
+// so that a service gives up when its caller does instead of working on an
+// answer nobody waits for anymore.
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header carries the remaining budget of a request, in milliseconds. A
+// relative budget, unlike an absolute deadline, is immune to clock skew
+// between the services.
+const Header = "X-Request-Budget"
+
+// Middleware returns a gin middleware bounding every request by limit or,
+// when trusted accepts the request, by the smaller budget in its [Header].
+// The header of a request that is not trusted is dropped, so that a client
+// can not shorten, or pass on, a budget of its own. A nil trusted trusts no
+// request.
+func Middleware(limit time.Duration, trusted func(*http.Request) bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		budget := limit
+		if trusted == nil || !trusted(ctx.Request) {
+			ctx.Request.Header.Del(Header)
+		} else if ms, err := strconv.ParseInt(ctx.GetHeader(Header), 10, 64); err == nil && ms >= 0 {
+			budget = min(budget, time.Duration(ms)*time.Millisecond)
+		}
+
+		trace.SpanFromContext(ctx.Request.Context()).SetAttributes(
+			attribute.Int64("deadline.budget_ms", budget.Milliseconds()),
+		)
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), budget)
+		defer cancel()
+
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
+
+// Inject sets the [Header] of a request to the budget left until the
+// deadline of ctx, if it has one.
+func Inject(ctx context.Context, h http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	h.Set(Header, strconv.FormatInt(max(0, time.Until(deadline).Milliseconds()), 10))
+}
+
+// AddressGetter bounds the calls to a [domain.AddressGetter] by a share of
+// the remaining budget, keeping the rest for the lookups that follow.
+type AddressGetter struct {
+	next  domain.AddressGetter
+	share float64
+}
+
+// NewAddressGetter returns a [domain.AddressGetter] giving next at most share,
+// between 0 and 1, of the time left until the deadline of each call.
+func NewAddressGetter(next domain.AddressGetter, share float64) domain.AddressGetter {
+	return &AddressGetter{next: next, share: share}
+}
+
+// GetAddress implements [domain.AddressGetter].
+func (d *AddressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return d.next.GetAddress(ctx, postalCode)
+	}
+
+	budget := time.Duration(float64(time.Until(deadline)) * d.share)
+
+	ctx, span := otel.Tracer("service-b").Start(ctx, "deadline-get-address")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int64("deadline.budget_ms", budget.Milliseconds()))
+
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	return d.next.GetAddress(ctx, postalCode)
+}
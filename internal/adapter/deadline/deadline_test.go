@@ -0,0 +1,143 @@
+package deadline_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/deadline"
+)
+
+type mockAddressGetter struct {
+	remaining time.Duration
+}
+
+func (m *mockAddressGetter) GetAddress(ctx context.Context, _ string) (domain.Address, error) {
+	if d, ok := ctx.Deadline(); ok {
+		m.remaining = time.Until(d)
+	}
+	return domain.Address{City: "São Paulo"}, nil
+}
+
+type DeadlineSuite struct {
+	suite.Suite
+}
+
+func TestDeadlineSuite(t *testing.T) {
+	suite.Run(t, new(DeadlineSuite))
+}
+
+// budget returns the budget a trusted request with the given header gets
+// from the middleware.
+func (s *DeadlineSuite) budget(limit time.Duration, header string) time.Duration {
+	return s.budgetTrusting(limit, header, func(*http.Request) bool { return true })
+}
+
+// budgetTrusting returns the budget a request with the given header gets
+// from the middleware trusting the requests trusted accepts.
+func (s *DeadlineSuite) budgetTrusting(limit time.Duration, header string, trusted func(*http.Request) bool) time.Duration {
+	gin.SetMode(gin.TestMode)
+
+	var remaining time.Duration
+	e := gin.New()
+	e.Use(deadline.Middleware(limit, trusted))
+	e.GET("/", func(ctx *gin.Context) {
+		d, ok := ctx.Request.Context().Deadline()
+		s.Require().True(ok)
+		remaining = time.Until(d)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(deadline.Header, header)
+	}
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	return remaining
+}
+
+func (s *DeadlineSuite) TestMiddlewareWithoutHeader() {
+	s.InDelta(time.Second, s.budget(time.Second, ""), float64(100*time.Millisecond))
+}
+
+func (s *DeadlineSuite) TestMiddlewareHonoursHeader() {
+	s.InDelta(200*time.Millisecond, s.budget(time.Second, "200"), float64(100*time.Millisecond))
+}
+
+func (s *DeadlineSuite) TestMiddlewareCapsHeader() {
+	s.InDelta(time.Second, s.budget(time.Second, "60000"), float64(100*time.Millisecond))
+}
+
+func (s *DeadlineSuite) TestMiddlewareIgnoresInvalidHeader() {
+	s.InDelta(time.Second, s.budget(time.Second, "soon"), float64(100*time.Millisecond))
+}
+
+func (s *DeadlineSuite) TestMiddlewareIgnoresUntrustedHeader() {
+	s.InDelta(time.Second, s.budgetTrusting(time.Second, "5", nil), float64(100*time.Millisecond))
+
+	untrusted := func(*http.Request) bool { return false }
+	s.InDelta(time.Second, s.budgetTrusting(time.Second, "5", untrusted), float64(100*time.Millisecond))
+}
+
+func (s *DeadlineSuite) TestMiddlewareDropsUntrustedHeader() {
+	gin.SetMode(gin.TestMode)
+
+	var header string
+	e := gin.New()
+	e.Use(deadline.Middleware(time.Second, nil))
+	e.GET("/", func(ctx *gin.Context) {
+		header = ctx.GetHeader(deadline.Header)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(deadline.Header, "5")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	s.Empty(header)
+}
+
+func (s *DeadlineSuite) TestInject() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	h := http.Header{}
+	deadline.Inject(ctx, h)
+
+	ms, err := strconv.Atoi(h.Get(deadline.Header))
+	s.Require().NoError(err)
+	s.InDelta(2000, ms, 100)
+}
+
+func (s *DeadlineSuite) TestInjectWithoutDeadline() {
+	h := http.Header{}
+	deadline.Inject(context.Background(), h)
+
+	s.Empty(h.Get(deadline.Header))
+}
+
+func (s *DeadlineSuite) TestAddressGetterShare() {
+	next := &mockAddressGetter{}
+	ag := deadline.NewAddressGetter(next, 0.25)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := ag.GetAddress(ctx, "01001000")
+	s.Require().NoError(err)
+	s.InDelta(250*time.Millisecond, next.remaining, float64(50*time.Millisecond))
+}
+
+func (s *DeadlineSuite) TestAddressGetterWithoutDeadline() {
+	next := &mockAddressGetter{}
+	ag := deadline.NewAddressGetter(next, 0.25)
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+	s.Require().NoError(err)
+	s.Zero(next.remaining)
+}
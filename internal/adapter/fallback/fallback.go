@@ -0,0 +1,176 @@
+// Package fallback composes several providers of the same domain interface,
+// trying them in order until one of them answers.
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrNoProviders is returned when a chain is built without any provider.
+var ErrNoProviders = errors.New("no providers configured")
+
+// AddressProvider is a named [domain.AddressGetter] taking part in a chain.
+type AddressProvider struct {
+	Name   string
+	Getter domain.AddressGetter
+}
+
+// AddressGetter tries each provider in order and returns the first address
+// found.
+type AddressGetter struct {
+	providers             []AddressProvider
+	authoritativeNotFound bool
+}
+
+// NewAddressGetter returns a [domain.AddressGetter] that tries providers in
+// the given order. When authoritativeNotFound is true, the first
+// [domain.ErrPostalCodeNotFound] ends the chain; otherwise the remaining
+// providers are still asked and not found is only reported if every one of
+// them answers so. When some of them fail instead, their errors are reported,
+// so that a CEP that could not be looked up is not taken, nor cached, as not
+// found.
+func NewAddressGetter(authoritativeNotFound bool, providers ...AddressProvider) (domain.AddressGetter, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	return &AddressGetter{providers: providers, authoritativeNotFound: authoritativeNotFound}, nil
+}
+
+// GetAddress implements [domain.AddressGetter]. The name of the provider that
+// answered is recorded in the span and, unless the provider already set it,
+// in [domain.Address.Source].
+func (f *AddressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "fallback-get-address")
+	defer span.End()
+
+	var (
+		errs      []error
+		notFounds int
+	)
+	for i, p := range f.providers {
+		addr, err := p.Getter.GetAddress(ctx, postalCode)
+		if err == nil {
+			if addr.Source == "" {
+				addr.Source = p.Name
+			}
+			span.SetAttributes(
+				attribute.String("address.provider", p.Name),
+				attribute.Int("address.attempts", i+1),
+			)
+			return addr, nil
+		}
+
+		span.AddEvent("provider-failed", trace.WithAttributes(
+			attribute.String("address.provider", p.Name),
+			attribute.String("error", err.Error()),
+		))
+
+		if errors.Is(err, domain.ErrPostalCodeNotFound) {
+			if f.authoritativeNotFound {
+				return domain.Address{}, domain.ErrPostalCodeNotFound
+			}
+			notFounds++
+			// not wrapped, so that the joined error is not taken for not
+			// found when another provider failed
+			errs = append(errs, fmt.Errorf("%s: %v", p.Name, err))
+		} else {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if notFounds == len(f.providers) {
+		// the message is part of the API contract, so it is not decorated
+		// with the provider names
+		return domain.Address{}, domain.ErrPostalCodeNotFound
+	}
+
+	err := errors.Join(errs...)
+	span.SetStatus(codes.Error, err.Error())
+	return domain.Address{}, err
+}
+
+// TemperatureProvider is a named [domain.TemperatureGetter] taking part in a
+// chain.
+type TemperatureProvider struct {
+	Name   string
+	Getter domain.TemperatureGetter
+}
+
+// TemperatureGetter tries each provider in order and returns the first
+// observation made. Unlike CEPs, a location one provider does not know may
+// be known by the next, so every error moves on to the next provider.
+type TemperatureGetter struct {
+	providers []TemperatureProvider
+}
+
+// NewTemperatureGetter returns a [domain.TemperatureGetter] that tries
+// providers in the given order. The returned value also implements
+// [domain.ObservationGetter].
+func NewTemperatureGetter(providers ...TemperatureProvider) (domain.TemperatureGetter, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	return &TemperatureGetter{providers: providers}, nil
+}
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (f *TemperatureGetter) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := f.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter]. The name of the
+// provider that answered is recorded in the span and, unless the provider
+// already set it, in [domain.Observation.Source].
+func (f *TemperatureGetter) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "fallback-get-observation")
+	defer span.End()
+
+	var errs []error
+	for i, p := range f.providers {
+		obs, err := domain.AsObservationGetter(p.Getter).GetObservation(ctx, location)
+		if err == nil {
+			if obs.Source == "" {
+				obs.Source = p.Name
+			}
+			span.SetAttributes(
+				attribute.String("temperature.provider", p.Name),
+				attribute.Int("temperature.attempts", i+1),
+			)
+			return obs, nil
+		}
+
+		span.AddEvent("provider-failed", trace.WithAttributes(
+			attribute.String("temperature.provider", p.Name),
+			attribute.String("error", err.Error()),
+		))
+
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	err := errors.Join(errs...)
+	span.SetStatus(codes.Error, err.Error())
+	return domain.Observation{}, err
+}
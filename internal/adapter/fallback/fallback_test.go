@@ -0,0 +1,212 @@
+package fallback_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/fallback"
+)
+
+type mockAddressGetter struct {
+	address domain.Address
+	err     error
+	calls   int
+}
+
+func (m *mockAddressGetter) GetAddress(_ context.Context, _ string) (domain.Address, error) {
+	m.calls++
+	return m.address, m.err
+}
+
+type mockTemperatureGetter struct {
+	temp  float64
+	err   error
+	calls int
+}
+
+func (m *mockTemperatureGetter) GetTemperature(_ context.Context, _ domain.Location) (float64, error) {
+	m.calls++
+	return m.temp, m.err
+}
+
+type FallbackSuite struct {
+	suite.Suite
+}
+
+func TestFallbackSuite(t *testing.T) {
+	suite.Run(t, new(FallbackSuite))
+}
+
+func (s *FallbackSuite) TestNoProviders() {
+	ag, err := fallback.NewAddressGetter(true)
+
+	s.ErrorIs(err, fallback.ErrNoProviders)
+	s.Nil(ag)
+}
+
+func (s *FallbackSuite) TestFirstProviderAnswers() {
+	first := &mockAddressGetter{address: domain.Address{City: "São Paulo", Source: "first"}}
+	second := &mockAddressGetter{address: domain.Address{City: "Rio de Janeiro"}}
+
+	ag, err := fallback.NewAddressGetter(true,
+		fallback.AddressProvider{Name: "first", Getter: first},
+		fallback.AddressProvider{Name: "second", Getter: second},
+	)
+	s.Require().NoError(err)
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+	s.Equal("first", addr.Source)
+	s.Zero(second.calls)
+}
+
+func (s *FallbackSuite) TestFallsBackOnError() {
+	first := &mockAddressGetter{err: errors.New("service unavailable")}
+	second := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+
+	ag, err := fallback.NewAddressGetter(true,
+		fallback.AddressProvider{Name: "first", Getter: first},
+		fallback.AddressProvider{Name: "second", Getter: second},
+	)
+	s.Require().NoError(err)
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+	s.Equal("second", addr.Source)
+}
+
+func (s *FallbackSuite) TestAuthoritativeNotFound() {
+	first := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	second := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+
+	ag, err := fallback.NewAddressGetter(true,
+		fallback.AddressProvider{Name: "first", Getter: first},
+		fallback.AddressProvider{Name: "second", Getter: second},
+	)
+	s.Require().NoError(err)
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Equal(domain.ErrPostalCodeNotFound.Error(), err.Error())
+	s.Empty(addr)
+	s.Zero(second.calls)
+}
+
+func (s *FallbackSuite) TestNonAuthoritativeNotFound() {
+	first := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	second := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+
+	ag, err := fallback.NewAddressGetter(false,
+		fallback.AddressProvider{Name: "first", Getter: first},
+		fallback.AddressProvider{Name: "second", Getter: second},
+	)
+	s.Require().NoError(err)
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+}
+
+func (s *FallbackSuite) TestEveryProviderNotFound() {
+	first := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	second := &mockAddressGetter{err: fmt.Errorf("decoding: %w", domain.ErrPostalCodeNotFound)}
+
+	ag, err := fallback.NewAddressGetter(false,
+		fallback.AddressProvider{Name: "first", Getter: first},
+		fallback.AddressProvider{Name: "second", Getter: second},
+	)
+	s.Require().NoError(err)
+
+	_, err = ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Equal(domain.ErrPostalCodeNotFound.Error(), err.Error())
+}
+
+func (s *FallbackSuite) TestNotFoundMixedWithErrors() {
+	unavailable := errors.New("service unavailable")
+	first := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	second := &mockAddressGetter{err: unavailable}
+
+	ag, err := fallback.NewAddressGetter(false,
+		fallback.AddressProvider{Name: "first", Getter: first},
+		fallback.AddressProvider{Name: "second", Getter: second},
+	)
+	s.Require().NoError(err)
+
+	_, err = ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorIs(err, unavailable)
+	s.NotErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.ErrorContains(err, "first: "+domain.ErrPostalCodeNotFound.Error())
+	s.Equal(1, second.calls)
+}
+
+func (s *FallbackSuite) TestAllProvidersFail() {
+	firstErr := errors.New("first failed")
+	secondErr := errors.New("second failed")
+
+	ag, err := fallback.NewAddressGetter(true,
+		fallback.AddressProvider{Name: "first", Getter: &mockAddressGetter{err: firstErr}},
+		fallback.AddressProvider{Name: "second", Getter: &mockAddressGetter{err: secondErr}},
+	)
+	s.Require().NoError(err)
+
+	_, err = ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorIs(err, firstErr)
+	s.ErrorIs(err, secondErr)
+	s.NotErrorIs(err, domain.ErrPostalCodeNotFound)
+}
+
+func (s *FallbackSuite) TestNoTemperatureProviders() {
+	tg, err := fallback.NewTemperatureGetter()
+
+	s.ErrorIs(err, fallback.ErrNoProviders)
+	s.Nil(tg)
+}
+
+func (s *FallbackSuite) TestTemperatureFallsBackOnQuota() {
+	first := &mockTemperatureGetter{err: domain.ErrRateLimited{Name: "weatherapi", RetryAfter: time.Hour}}
+	second := &mockTemperatureGetter{temp: 25}
+
+	tg, err := fallback.NewTemperatureGetter(
+		fallback.TemperatureProvider{Name: "weatherapi", Getter: first},
+		fallback.TemperatureProvider{Name: "wttr", Getter: second},
+	)
+	s.Require().NoError(err)
+
+	obs, err := tg.(domain.ObservationGetter).GetObservation(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.NoError(err)
+	s.Equal(25.0, obs.TempC)
+	s.Equal("wttr", obs.Source)
+	s.Equal(1, first.calls)
+}
+
+func (s *FallbackSuite) TestAllTemperatureProvidersFail() {
+	first := &mockTemperatureGetter{err: errors.New("first down")}
+	second := &mockTemperatureGetter{err: errors.New("second down")}
+
+	tg, err := fallback.NewTemperatureGetter(
+		fallback.TemperatureProvider{Name: "first", Getter: first},
+		fallback.TemperatureProvider{Name: "second", Getter: second},
+	)
+	s.Require().NoError(err)
+
+	_, err = tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.ErrorContains(err, "first: first down")
+	s.ErrorContains(err, "second: second down")
+}
@@ -0,0 +1,104 @@
+package hedge
+
+import (
+	"context"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+)
+
+// AddressGetter hedges the calls to a [domain.AddressGetter].
+type AddressGetter struct {
+	primary   domain.AddressGetter
+	alternate domain.AddressGetter
+	hedger    *Hedger
+}
+
+// NewAddressGetter returns a [domain.AddressGetter] that calls primary and,
+// when it is slow, alternate too. A nil alternate hedges with primary
+// itself.
+func NewAddressGetter(primary, alternate domain.AddressGetter, name string, settings Settings) domain.AddressGetter {
+	return New(name, settings).AddressGetter(primary, alternate)
+}
+
+// AddressGetter returns a [domain.AddressGetter] hedging primary with
+// alternate through h, so that the latencies observed outlive the getter.
+// A nil alternate hedges with primary itself.
+func (h *Hedger) AddressGetter(primary, alternate domain.AddressGetter) domain.AddressGetter {
+	if alternate == nil {
+		alternate = primary
+	}
+
+	return &AddressGetter{primary: primary, alternate: alternate, hedger: h}
+}
+
+// GetAddress implements [domain.AddressGetter].
+func (h *AddressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "hedge-get-address")
+	defer span.End()
+
+	primary := func(ctx context.Context) (domain.Address, error) {
+		return h.primary.GetAddress(ctx, postalCode)
+	}
+	alternate := func(ctx context.Context) (domain.Address, error) {
+		return h.alternate.GetAddress(ctx, postalCode)
+	}
+
+	return do(ctx, h.hedger, primary, alternate)
+}
+
+// TemperatureGetter hedges the calls to a [domain.TemperatureGetter].
+type TemperatureGetter struct {
+	primary   domain.ObservationGetter
+	alternate domain.ObservationGetter
+	hedger    *Hedger
+}
+
+// NewTemperatureGetter returns a [domain.TemperatureGetter] that calls
+// primary and, when it is slow, alternate too. A nil alternate hedges with
+// primary itself. The returned value also implements
+// [domain.ObservationGetter].
+func NewTemperatureGetter(primary, alternate domain.TemperatureGetter, name string, settings Settings) domain.TemperatureGetter {
+	return New(name, settings).TemperatureGetter(primary, alternate)
+}
+
+// TemperatureGetter returns a [domain.TemperatureGetter] hedging primary with
+// alternate through h, so that the latencies observed outlive the getter.
+// A nil alternate hedges with primary itself. The returned value also
+// implements [domain.ObservationGetter].
+func (h *Hedger) TemperatureGetter(primary, alternate domain.TemperatureGetter) domain.TemperatureGetter {
+	if alternate == nil {
+		alternate = primary
+	}
+
+	return &TemperatureGetter{
+		primary:   domain.AsObservationGetter(primary),
+		alternate: domain.AsObservationGetter(alternate),
+		hedger:    h,
+	}
+}
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (h *TemperatureGetter) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := h.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter].
+func (h *TemperatureGetter) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "hedge-get-observation")
+	defer span.End()
+
+	primary := func(ctx context.Context) (domain.Observation, error) {
+		return h.primary.GetObservation(ctx, location)
+	}
+	alternate := func(ctx context.Context) (domain.Observation, error) {
+		return h.alternate.GetObservation(ctx, location)
+	}
+
+	return do(ctx, h.hedger, primary, alternate)
+}
@@ -0,0 +1,169 @@
+// Package hedge decorates [domain.AddressGetter] and
+// [domain.TemperatureGetter] with hedged requests: when the first call takes
+// longer than usual, a second one is made, to the same or an alternate
+// provider, and whichever answers first wins while the other is cancelled.
+package hedge
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Settings configures when the hedged call is made.
+type Settings struct {
+	// Delay is how long the first call is waited for before hedging.
+	Delay time.Duration
+	// Percentile, when set, replaces Delay by that percentile, between 0 and
+	// 1, of the latencies recently observed, once there are MinSamples of
+	// them. Delay is still used until then.
+	Percentile float64
+	// MinSamples is how many latencies are needed to use Percentile.
+	MinSamples int
+}
+
+// samples is how many of the latest latencies are kept for Percentile.
+const samples = 200
+
+// Hedger runs calls with hedging. It is safe for concurrent use.
+type Hedger struct {
+	name     string
+	settings Settings
+	hedges   metric.Int64Counter
+	wins     metric.Int64Counter
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+// New returns a [Hedger] identified by name in spans and metrics.
+func New(name string, settings Settings) *Hedger {
+	meter := otel.Meter("service-b")
+	hedges, _ := meter.Int64Counter(
+		"hedge.requests",
+		metric.WithDescription("Hedged calls made after the first one was slow"),
+	)
+	wins, _ := meter.Int64Counter(
+		"hedge.wins",
+		metric.WithDescription("Hedged calls that answered before the first one"),
+	)
+
+	return &Hedger{name: name, settings: settings, hedges: hedges, wins: wins}
+}
+
+// Delay returns how long the first call is currently waited for.
+func (h *Hedger) Delay() time.Duration {
+	if h.settings.Percentile <= 0 {
+		return h.settings.Delay
+	}
+
+	h.mu.Lock()
+	sorted := slices.Clone(h.latencies)
+	h.mu.Unlock()
+
+	if len(sorted) == 0 || len(sorted) < h.settings.MinSamples {
+		return h.settings.Delay
+	}
+
+	slices.Sort(sorted)
+	return sorted[min(len(sorted)-1, int(float64(len(sorted))*h.settings.Percentile))]
+}
+
+func (h *Hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < samples {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % samples
+}
+
+type result[T any] struct {
+	value T
+	err   error
+	hedge bool
+}
+
+// do calls primary and, if it has not answered after the hedger delay,
+// hedge too. The first answer wins and the other call is cancelled. A
+// failure only wins when both calls fail, or when primary fails before the
+// delay, which is left to the retries.
+func do[T any](ctx context.Context, h *Hedger, primary, hedge func(context.Context) (T, error)) (T, error) {
+	span := trace.SpanFromContext(ctx)
+	attrs := metric.WithAttributes(attribute.String("hedge", h.name))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered, so the loser never blocks
+	results := make(chan result[T], 2)
+	start := func(fn func(context.Context) (T, error), isHedge bool) {
+		go func() {
+			begin := time.Now()
+			v, err := fn(ctx)
+			if answered(err) {
+				h.observe(time.Since(begin))
+			}
+			results <- result[T]{value: v, err: err, hedge: isHedge}
+		}()
+	}
+
+	start(primary, false)
+	pending := 1
+
+	timer := time.NewTimer(h.Delay())
+	defer timer.Stop()
+	fire := timer.C
+
+	var failed *result[T]
+
+	for {
+		select {
+		case <-fire:
+			fire = nil
+			span.AddEvent("hedge-fired", trace.WithAttributes(attribute.String("hedge.name", h.name)))
+			h.hedges.Add(ctx, 1, attrs)
+			start(hedge, true)
+			pending++
+		case r := <-results:
+			pending--
+			hedged := fire == nil
+			span.SetAttributes(attribute.Bool("hedge.fired", hedged))
+
+			if answered(r.err) {
+				winner := "primary"
+				if r.hedge {
+					winner = "hedge"
+					h.wins.Add(ctx, 1, attrs)
+				}
+				span.SetAttributes(attribute.String("hedge.winner", winner))
+				return r.value, r.err
+			}
+
+			if failed == nil {
+				failed = &r
+			}
+			if !hedged || pending == 0 {
+				return failed.value, failed.err
+			}
+		}
+	}
+}
+
+// answered reports whether err, returned by a call, is an answer rather than
+// a failure.
+func answered(err error) bool {
+	return err == nil || errors.Is(err, domain.ErrPostalCodeNotFound)
+}
@@ -0,0 +1,202 @@
+package hedge_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/hedge"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+var errUpstream = errors.New("upstream unavailable")
+
+// slowAddressGetter answers after delay, or fails with the context error
+// when cancelled first.
+type slowAddressGetter struct {
+	city      string
+	delay     time.Duration
+	err       error
+	calls     atomic.Int32
+	cancelled atomic.Int32
+}
+
+func (m *slowAddressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	m.calls.Add(1)
+	select {
+	case <-time.After(m.delay):
+		if m.err != nil {
+			return domain.Address{}, m.err
+		}
+		return domain.Address{PostalCode: postalCode, City: m.city}, nil
+	case <-ctx.Done():
+		m.cancelled.Add(1)
+		return domain.Address{}, ctx.Err()
+	}
+}
+
+type mockTemperatureGetter struct {
+	temp  float64
+	delay time.Duration
+}
+
+func (m *mockTemperatureGetter) GetTemperature(ctx context.Context, _ domain.Location) (float64, error) {
+	select {
+	case <-time.After(m.delay):
+		return m.temp, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+type HedgeSuite struct {
+	suite.Suite
+}
+
+func TestHedgeSuite(t *testing.T) {
+	suite.Run(t, new(HedgeSuite))
+}
+
+func (s *HedgeSuite) TestFastPrimaryIsNotHedged() {
+	primary := &slowAddressGetter{city: "primary", delay: time.Millisecond}
+	alternate := &slowAddressGetter{city: "alternate"}
+	ag := hedge.NewAddressGetter(primary, alternate, "cep", hedge.Settings{Delay: 100 * time.Millisecond})
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("primary", addr.City)
+	s.Zero(alternate.calls.Load())
+}
+
+func (s *HedgeSuite) TestSlowPrimaryIsHedged() {
+	primary := &slowAddressGetter{city: "primary", delay: time.Second}
+	alternate := &slowAddressGetter{city: "alternate", delay: time.Millisecond}
+	ag := hedge.NewAddressGetter(primary, alternate, "cep", hedge.Settings{Delay: 10 * time.Millisecond})
+
+	start := time.Now()
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("alternate", addr.City)
+	s.Less(time.Since(start), 500*time.Millisecond)
+	s.Eventually(func() bool { return primary.cancelled.Load() == 1 }, time.Second, time.Millisecond)
+}
+
+func (s *HedgeSuite) TestPrimaryStillWins() {
+	primary := &slowAddressGetter{city: "primary", delay: 20 * time.Millisecond}
+	alternate := &slowAddressGetter{city: "alternate", delay: time.Second}
+	ag := hedge.NewAddressGetter(primary, alternate, "cep", hedge.Settings{Delay: 5 * time.Millisecond})
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("primary", addr.City)
+	s.Equal(int32(1), alternate.calls.Load())
+	s.Eventually(func() bool { return alternate.cancelled.Load() == 1 }, time.Second, time.Millisecond)
+}
+
+func (s *HedgeSuite) TestHedgesWithPrimaryItself() {
+	primary := &slowAddressGetter{city: "primary", delay: 20 * time.Millisecond}
+	ag := hedge.NewAddressGetter(primary, nil, "cep", hedge.Settings{Delay: 5 * time.Millisecond})
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal(int32(2), primary.calls.Load())
+}
+
+func (s *HedgeSuite) TestFailureWaitsForOtherCall() {
+	primary := &slowAddressGetter{delay: 20 * time.Millisecond, err: errUpstream}
+	alternate := &slowAddressGetter{city: "alternate", delay: 40 * time.Millisecond}
+	ag := hedge.NewAddressGetter(primary, alternate, "cep", hedge.Settings{Delay: 5 * time.Millisecond})
+
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal("alternate", addr.City)
+}
+
+func (s *HedgeSuite) TestBothFail() {
+	primary := &slowAddressGetter{delay: 20 * time.Millisecond, err: errUpstream}
+	alternate := &slowAddressGetter{delay: 20 * time.Millisecond, err: errors.New("other")}
+	ag := hedge.NewAddressGetter(primary, alternate, "cep", hedge.Settings{Delay: 5 * time.Millisecond})
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorIs(err, errUpstream)
+}
+
+func (s *HedgeSuite) TestNotFoundIsAnAnswer() {
+	primary := &slowAddressGetter{delay: 20 * time.Millisecond, err: domain.ErrPostalCodeNotFound}
+	alternate := &slowAddressGetter{city: "alternate", delay: time.Second}
+	ag := hedge.NewAddressGetter(primary, alternate, "cep", hedge.Settings{Delay: 5 * time.Millisecond})
+
+	_, err := ag.GetAddress(context.Background(), "99999999")
+
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+}
+
+func (s *HedgeSuite) TestPercentileDelay() {
+	h := hedge.New("cep", hedge.Settings{Delay: time.Second, Percentile: 0.95, MinSamples: 3})
+	s.Equal(time.Second, h.Delay())
+
+	primary := &slowAddressGetter{city: "primary", delay: 10 * time.Millisecond}
+	ag := hedge.NewAddressGetter(primary, nil, "cep", hedge.Settings{Delay: time.Second, Percentile: 0.95, MinSamples: 3})
+	for range 3 {
+		_, err := ag.GetAddress(context.Background(), "01001000")
+		s.NoError(err)
+	}
+
+	// the fourth call is hedged after about 10ms instead of a second
+	primary.delay = 200 * time.Millisecond
+	start := time.Now()
+	_, err := ag.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+	s.Equal(int32(5), primary.calls.Load())
+	s.Less(time.Since(start), time.Second)
+}
+
+func (s *HedgeSuite) TestTemperatureGetter() {
+	primary := &mockTemperatureGetter{temp: 10, delay: time.Second}
+	alternate := &mockTemperatureGetter{temp: 20, delay: time.Millisecond}
+	tg := hedge.NewTemperatureGetter(primary, alternate, "temperature", hedge.Settings{Delay: 5 * time.Millisecond})
+
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.NoError(err)
+	s.Equal(20.0, temp)
+}
+
+func (s *HedgeSuite) TestMetrics() {
+	reader := sdkmetric.NewManualReader()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(noop.NewMeterProvider())
+
+	primary := &slowAddressGetter{city: "primary", delay: time.Second}
+	alternate := &slowAddressGetter{city: "alternate", delay: time.Millisecond}
+	ag := hedge.NewAddressGetter(primary, alternate, "cep", hedge.Settings{Delay: 5 * time.Millisecond})
+
+	_, err := ag.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+
+	var rm metricdata.ResourceMetrics
+	s.Require().NoError(reader.Collect(context.Background(), &rm))
+	s.Require().Len(rm.ScopeMetrics, 1)
+
+	values := map[string]int64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		sum, ok := m.Data.(metricdata.Sum[int64])
+		s.Require().True(ok)
+		s.Require().Len(sum.DataPoints, 1)
+		values[m.Name] = sum.DataPoints[0].Value
+	}
+	s.Equal(map[string]int64{"hedge.requests": 1, "hedge.wins": 1}, values)
+}
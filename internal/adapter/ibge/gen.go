@@ -0,0 +1,111 @@
+//go:build ignore
+
+// Gen writes municipios.csv, the table embedded by the package, from the
+// public municipios.csv dataset derived from IBGE data, keeping only the
+// columns the geocoder reads.
+//
+//	go generate ./internal/adapter/ibge
+//
+// A local copy of the dataset can be given as the first argument instead.
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+const sourceURL = "https://raw.githubusercontent.com/kelvins/municipios-brasileiros/main/csv/municipios.csv"
+
+var columns = []string{"codigo_ibge", "nome", "latitude", "longitude"}
+
+func main() {
+	r, err := open(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+
+	rows, err := read(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rows) < 5000 {
+		log.Fatalf("only %d municipalities read, expected the whole country", len(rows))
+	}
+
+	f, err := os.Create("municipios.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := csv.NewWriter(f)
+	_ = w.Write(columns)
+	_ = w.WriteAll(rows)
+
+	if err := errors.Join(w.Error(), f.Close()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func open(args []string) (io.ReadCloser, error) {
+	if len(args) > 0 {
+		return os.Open(args[0])
+	}
+
+	res, err := http.Get(sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("downloading table: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("downloading table: status %d", res.StatusCode)
+	}
+
+	return res.Body, nil
+}
+
+// read returns the rows of the table with only the geocoder columns, sorted
+// by IBGE code.
+func read(r io.Reader) ([][]string, error) {
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	idx := make([]int, len(columns))
+	for i, name := range columns {
+		if idx[i] = slices.Index(header, name); idx[i] < 0 {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var rows [][]string
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading record: %w", err)
+		}
+
+		row := make([]string, len(idx))
+		for i, j := range idx {
+			row[i] = rec[j]
+		}
+		rows = append(rows, row)
+	}
+
+	slices.SortFunc(rows, func(a, b []string) int { return strings.Compare(a[0], b[0]) })
+
+	return rows, nil
+}
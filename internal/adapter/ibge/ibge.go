@@ -0,0 +1,160 @@
+// Package ibge implements an offline [domain.Geocoder] backed by a table of
+// IBGE municipality coordinates.
+//
+// The table is a CSV with a header containing at least the columns
+// codigo_ibge, nome, latitude and longitude, which is the layout of the
+// public municipios.csv dataset derived from IBGE data. Extra columns are
+// ignored. The embedded table is written by gen.go with go generate; a
+// table can also be loaded from a file with [NewGeocoderFromFile].
+package ibge
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:generate go run gen.go
+
+//go:embed municipios.csv
+var municipios []byte
+
+// ErrMunicipalityNotFound is returned when the address matches no
+// municipality in the table.
+var ErrMunicipalityNotFound = errors.New("municipality not found")
+
+// ufByCode maps the first two digits of an IBGE municipality code to the UF
+// of its state.
+var ufByCode = map[string]string{
+	"11": "RO", "12": "AC", "13": "AM", "14": "RR", "15": "PA", "16": "AP",
+	"17": "TO", "21": "MA", "22": "PI", "23": "CE", "24": "RN", "25": "PB",
+	"26": "PE", "27": "AL", "28": "SE", "29": "BA", "31": "MG", "32": "ES",
+	"33": "RJ", "35": "SP", "41": "PR", "42": "SC", "43": "RS", "50": "MS",
+	"51": "MT", "52": "GO", "53": "DF",
+}
+
+// Geocoder is a [domain.Geocoder] that resolves addresses by IBGE code or,
+// failing that, by city name and UF.
+type Geocoder struct {
+	byCode map[string]domain.Coordinates
+	byName map[string]domain.Coordinates
+}
+
+// NewGeocoder returns a [domain.Geocoder] backed by the embedded table.
+func NewGeocoder() (domain.Geocoder, error) {
+	return NewGeocoderFromReader(bytes.NewReader(municipios))
+}
+
+// NewGeocoderFromFile returns a [domain.Geocoder] backed by the table in the
+// CSV file at path.
+func NewGeocoderFromFile(path string) (domain.Geocoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening municipality table: %w", err)
+	}
+	defer f.Close()
+
+	return NewGeocoderFromReader(f)
+}
+
+// NewGeocoderFromReader returns a [domain.Geocoder] backed by the CSV table
+// read from r.
+func NewGeocoderFromReader(r io.Reader) (domain.Geocoder, error) {
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"codigo_ibge", "nome", "latitude", "longitude"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	g := &Geocoder{
+		byCode: make(map[string]domain.Coordinates),
+		byName: make(map[string]domain.Coordinates),
+	}
+
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading record: %w", err)
+		}
+
+		code := rec[cols["codigo_ibge"]]
+
+		lat, err := strconv.ParseFloat(rec[cols["latitude"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing latitude of %s: %w", code, err)
+		}
+
+		lon, err := strconv.ParseFloat(rec[cols["longitude"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing longitude of %s: %w", code, err)
+		}
+
+		coords := domain.Coordinates{Latitude: lat, Longitude: lon}
+		g.byCode[code] = coords
+		if len(code) >= 2 {
+			g.byName[nameKey(rec[cols["nome"]], ufByCode[code[:2]])] = coords
+		}
+	}
+
+	return g, nil
+}
+
+// Geocode implements [domain.Geocoder].
+func (g *Geocoder) Geocode(ctx context.Context, address domain.Address) (domain.Coordinates, error) {
+	_, span := otel.Tracer("service-b").Start(ctx, "geocode")
+	defer span.End()
+
+	if coords, ok := g.byCode[address.IBGE]; ok {
+		span.SetAttributes(attribute.String("geocode.match", "ibge"))
+		return coords, nil
+	}
+
+	if coords, ok := g.byName[nameKey(address.City, address.UF)]; ok {
+		span.SetAttributes(attribute.String("geocode.match", "name"))
+		return coords, nil
+	}
+
+	return domain.Coordinates{}, ErrMunicipalityNotFound
+}
+
+// nameKey normalizes a municipality name and UF so that differences in case
+// and accents ("SÃO PAULO", "Sao Paulo") do not matter.
+func nameKey(name, uf string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+	name, _, err := transform.String(t, name)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(name)) + "/" + strings.ToUpper(strings.TrimSpace(uf))
+}
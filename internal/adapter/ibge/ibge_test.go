@@ -0,0 +1,104 @@
+package ibge_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/ibge"
+)
+
+type IBGESuite struct {
+	suite.Suite
+}
+
+func TestIBGESuite(t *testing.T) {
+	suite.Run(t, new(IBGESuite))
+}
+
+func (s *IBGESuite) TestEmbeddedTableByCode() {
+	gc, err := ibge.NewGeocoder()
+	s.Require().NoError(err)
+
+	coords, err := gc.Geocode(context.Background(), domain.Address{IBGE: "3550308"})
+
+	s.NoError(err)
+	s.Equal(domain.Coordinates{Latitude: -23.5329, Longitude: -46.6395}, coords)
+}
+
+func (s *IBGESuite) TestByNameIgnoresCaseAndAccents() {
+	gc, err := ibge.NewGeocoder()
+	s.Require().NoError(err)
+
+	coords, err := gc.Geocode(context.Background(), domain.Address{City: "SAO PAULO", UF: "sp"})
+
+	s.NoError(err)
+	s.Equal(domain.Coordinates{Latitude: -23.5329, Longitude: -46.6395}, coords)
+}
+
+func (s *IBGESuite) TestDuplicatedNamesAreResolvedByUF() {
+	table := "codigo_ibge,nome,latitude,longitude,capital\n" +
+		"2201903,Bom Jesus,-9.07,-44.36,0\n" +
+		"4302501,Bom Jesus,-28.67,-50.43,0\n"
+
+	gc, err := ibge.NewGeocoderFromReader(strings.NewReader(table))
+	s.Require().NoError(err)
+
+	pi, err := gc.Geocode(context.Background(), domain.Address{City: "Bom Jesus", UF: "PI"})
+	s.NoError(err)
+	s.Equal(-9.07, pi.Latitude)
+
+	rs, err := gc.Geocode(context.Background(), domain.Address{City: "Bom Jesus", UF: "RS"})
+	s.NoError(err)
+	s.Equal(-28.67, rs.Latitude)
+}
+
+func (s *IBGESuite) TestEmbeddedTableResolvesDuplicatedNames() {
+	gc, err := ibge.NewGeocoder()
+	s.Require().NoError(err)
+
+	pi, err := gc.Geocode(context.Background(), domain.Address{City: "Bom Jesus", UF: "PI"})
+	if errors.Is(err, ibge.ErrMunicipalityNotFound) {
+		s.T().Skip("the embedded table only has the state capitals; run go generate to embed the complete one")
+	}
+	s.Require().NoError(err)
+
+	rs, err := gc.Geocode(context.Background(), domain.Address{City: "Bom Jesus", UF: "RS"})
+	s.Require().NoError(err)
+	s.NotEqual(pi, rs)
+
+	pb, err := gc.Geocode(context.Background(), domain.Address{City: "Santa Rita", UF: "PB"})
+	s.Require().NoError(err)
+	ma, err := gc.Geocode(context.Background(), domain.Address{City: "Santa Rita", UF: "MA"})
+	s.Require().NoError(err)
+	s.NotEqual(pb, ma)
+}
+
+func (s *IBGESuite) TestNotFound() {
+	gc, err := ibge.NewGeocoder()
+	s.Require().NoError(err)
+
+	coords, err := gc.Geocode(context.Background(), domain.Address{City: "Nowhere", UF: "SP"})
+
+	s.ErrorIs(err, ibge.ErrMunicipalityNotFound)
+	s.Zero(coords)
+}
+
+func (s *IBGESuite) TestMissingColumn() {
+	gc, err := ibge.NewGeocoderFromReader(strings.NewReader("codigo_ibge,nome,latitude\n"))
+
+	s.Error(err)
+	s.Nil(gc)
+}
+
+func (s *IBGESuite) TestInvalidCoordinate() {
+	table := "codigo_ibge,nome,latitude,longitude\n3550308,São Paulo,abc,-46.6395\n"
+
+	gc, err := ibge.NewGeocoderFromReader(strings.NewReader(table))
+
+	s.Error(err)
+	s.Nil(gc)
+}
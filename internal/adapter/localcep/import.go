@@ -0,0 +1,273 @@
+package localcep
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	bolt "go.etcd.io/bbolt"
+)
+
+// batchSize is how many records are written per transaction while
+// importing.
+const batchSize = 10000
+
+// Import builds the index at path from the CSV read from r, replacing any
+// previous index only once the new one is complete. Services holding the
+// previous index open keep reading it until they reopen path. It returns how
+// many records were imported.
+func Import(ctx context.Context, path string, r io.Reader) (int, error) {
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+
+	n, err := build(ctx, tmp, r)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return 0, fmt.Errorf("replacing index: %w", err)
+	}
+
+	return n, nil
+}
+
+// ImportFile is like [Import], reading the CSV file at csvPath.
+func ImportFile(ctx context.Context, path, csvPath string) (int, error) {
+	f, err := os.Open(csvPath)
+	if err != nil {
+		return 0, fmt.Errorf("opening CEP table: %w", err)
+	}
+	defer f.Close()
+
+	return Import(ctx, path, f)
+}
+
+func build(ctx context.Context, path string, r io.Reader) (int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return 0, fmt.Errorf("reading header: %w", err)
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"cep", "localidade", "uf"} {
+		if _, ok := cols[name]; !ok {
+			return 0, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	field := func(rec []string, name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer db.Close()
+
+	// the index is only renamed into place once complete, so losing it to
+	// a crash is harmless
+	db.NoSync = true
+
+	var (
+		n      int
+		batch  [][]string
+		ranges []segment
+	)
+
+	flush := func() error {
+		err := db.Update(func(tx *bolt.Tx) error {
+			exact, err := tx.CreateBucketIfNotExists(exactBucket)
+			if err != nil {
+				return err
+			}
+
+			for _, rec := range batch {
+				cep := domain.NormalizePostalCode(field(rec, "cep"))
+				end := domain.NormalizePostalCode(field(rec, "cep_fim"))
+				if len(cep) != 8 || (end != "" && len(end) != 8) {
+					return fmt.Errorf("invalid CEP %q", field(rec, "cep"))
+				}
+
+				addr := domain.Address{
+					Street:       field(rec, "logradouro"),
+					Neighborhood: field(rec, "bairro"),
+					City:         field(rec, "localidade"),
+					UF:           field(rec, "uf"),
+					State:        field(rec, "estado"),
+					Region:       field(rec, "regiao"),
+					IBGE:         field(rec, "ibge"),
+					DDD:          field(rec, "ddd"),
+				}
+
+				if end == "" || end == cep {
+					b, err := json.Marshal(addr)
+					if err != nil {
+						return err
+					}
+					if err := exact.Put([]byte(cep), b); err != nil {
+						return err
+					}
+					continue
+				}
+
+				if end < cep {
+					return fmt.Errorf("range %s-%s ends before it starts", cep, end)
+				}
+
+				// written once every range is known, see flatten
+				start, _ := strconv.Atoi(cep)
+				last, _ := strconv.Atoi(end)
+				ranges = append(ranges, segment{start: start, end: last, id: len(ranges), addr: addr})
+			}
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("writing records: %w", err)
+		}
+
+		n += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("reading record: %w", err)
+		}
+
+		batch = append(batch, rec)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	if err := writeRanges(db, flatten(ranges)); err != nil {
+		return 0, err
+	}
+
+	if err := db.Sync(); err != nil {
+		return 0, fmt.Errorf("syncing index: %w", err)
+	}
+
+	return n, nil
+}
+
+// segment is a range of CEPs, as numbers. id tells the ranges of the CSV
+// apart once split by [flatten].
+type segment struct {
+	start, end int
+	id         int
+	addr       domain.Address
+}
+
+// flatten splits ranges, which may be nested, into ranges that do not
+// overlap, so that a lookup only has to read the range starting at or right
+// before a CEP. Every CEP keeps the range covering it with the greatest
+// start, which is the narrowest one when ranges are nested.
+func flatten(ranges []segment) []segment {
+	slices.SortStableFunc(ranges, func(a, b segment) int { return cmp.Compare(a.start, b.start) })
+
+	// the covering range can only change where a range starts or ends
+	bounds := make([]int, 0, 2*len(ranges))
+	for _, r := range ranges {
+		bounds = append(bounds, r.start, r.end+1)
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	var (
+		flat []segment
+		// the ranges started so far, the last one having the greatest start
+		open []segment
+		next int
+	)
+	for i, at := range bounds[:max(0, len(bounds)-1)] {
+		for ; next < len(ranges) && ranges[next].start <= at; next++ {
+			open = append(open, ranges[next])
+		}
+		for len(open) > 0 && open[len(open)-1].end < at {
+			open = open[:len(open)-1]
+		}
+		if len(open) == 0 {
+			continue
+		}
+
+		r, end := open[len(open)-1], bounds[i+1]-1
+		if n := len(flat); n > 0 && flat[n-1].id == r.id && flat[n-1].end == at-1 {
+			flat[n-1].end = end
+			continue
+		}
+		flat = append(flat, segment{start: at, end: end, id: r.id, addr: r.addr})
+	}
+
+	return flat
+}
+
+func writeRanges(db *bolt.DB, ranges []segment) error {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(rangesBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("creating ranges: %w", err)
+	}
+
+	for batch := range slices.Chunk(ranges, batchSize) {
+		err := db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket(rangesBucket)
+			for _, r := range batch {
+				v, err := json.Marshal(cepRange{End: fmt.Sprintf("%08d", r.end), Address: r.addr})
+				if err != nil {
+					return err
+				}
+				if err := b.Put(fmt.Appendf(nil, "%08d", r.start), v); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("writing ranges: %w", err)
+		}
+	}
+
+	return nil
+}
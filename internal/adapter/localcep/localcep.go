@@ -0,0 +1,122 @@
+// Package localcep implements an offline [domain.AddressGetter] backed by a
+// local index of CEPs, for environments without internet access.
+//
+// The index is built by [Import] from a CSV in the ViaCEP field layout: a
+// header containing at least the columns cep, localidade and uf, plus any of
+// logradouro, bairro, estado, regiao, ibge and ddd. A record with a cep_fim
+// column covers every CEP from cep to cep_fim, which is how the single
+// "generic" CEPs of smaller cities (e.g. 78890-000) are represented in the
+// DNE. Extra columns are ignored.
+package localcep
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	bolt "go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	exactBucket  = []byte("exact")
+	rangesBucket = []byte("ranges")
+)
+
+// LocalCEP is a [domain.AddressGetter] reading from an index built by
+// [Import].
+type LocalCEP struct {
+	db *bolt.DB
+}
+
+// Open opens, read-only, the index at path.
+func Open(path string) (*LocalCEP, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	return &LocalCEP{db: db}, nil
+}
+
+// GetAddress implements [domain.AddressGetter]. CEPs absent from the index
+// and outside every range return [domain.ErrPostalCodeNotFound].
+func (l *LocalCEP) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	_, span := otel.Tracer("service-b").Start(ctx, "get-address")
+	defer span.End()
+
+	cep := domain.NormalizePostalCode(postalCode)
+	if len(cep) != 8 {
+		return domain.Address{}, domain.ErrPostalCodeNotFound
+	}
+
+	var (
+		addr  domain.Address
+		match string
+	)
+
+	err := l.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(exactBucket).Get([]byte(cep)); v != nil {
+			match = "exact"
+			return json.Unmarshal(v, &addr)
+		}
+
+		r, ok := findRange(tx.Bucket(rangesBucket), []byte(cep))
+		if !ok {
+			return domain.ErrPostalCodeNotFound
+		}
+
+		match = "range"
+		addr = r.Address
+		return nil
+	})
+	if err != nil {
+		return domain.Address{}, err
+	}
+
+	span.SetAttributes(attribute.String("localcep.match", match))
+
+	addr.PostalCode = cep
+	addr.Source = "localcep"
+
+	return addr, nil
+}
+
+// Close closes the index.
+func (l *LocalCEP) Close() error {
+	return l.db.Close()
+}
+
+// cepRange is what the ranges bucket keeps, keyed by the first CEP. The
+// ranges are stored without overlaps, see [flatten].
+type cepRange struct {
+	End     string         `json:"end"`
+	Address domain.Address `json:"address"`
+}
+
+// findRange returns the range covering cep. Since the ranges do not overlap,
+// only the one starting at or right before cep can cover it.
+func findRange(b *bolt.Bucket, cep []byte) (cepRange, bool) {
+	c := b.Cursor()
+
+	k, v := c.Seek(cep)
+	switch {
+	case k == nil:
+		k, v = c.Last()
+	case !bytes.Equal(k, cep):
+		k, v = c.Prev()
+	}
+	if k == nil {
+		return cepRange{}, false
+	}
+
+	var r cepRange
+	if err := json.Unmarshal(v, &r); err != nil || r.End < string(cep) {
+		return cepRange{}, false
+	}
+
+	return r, true
+}
@@ -0,0 +1,180 @@
+package localcep_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/localcep"
+)
+
+const table = `cep,logradouro,complemento,bairro,localidade,uf,estado,regiao,ibge,gia,ddd,siafi,cep_fim
+01001-000,Praça da Sé,lado ímpar,Sé,São Paulo,SP,São Paulo,Sudeste,3550308,1004,11,7107,
+78890-000,,,,Sorriso,MT,Mato Grosso,Centro-Oeste,5107925,,66,9937,78899-999
+78895-000,,,,Distrito de Boa Esperança,MT,Mato Grosso,Centro-Oeste,5107925,,66,9937,78895-999
+`
+
+type LocalCEPSuite struct {
+	suite.Suite
+	path string
+}
+
+func TestLocalCEPSuite(t *testing.T) {
+	suite.Run(t, new(LocalCEPSuite))
+}
+
+func (s *LocalCEPSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "ceps.db")
+
+	n, err := localcep.Import(context.Background(), s.path, strings.NewReader(table))
+	s.Require().NoError(err)
+	s.Require().Equal(3, n)
+}
+
+func (s *LocalCEPSuite) open() *localcep.LocalCEP {
+	l, err := localcep.Open(s.path)
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { l.Close() })
+	return l
+}
+
+func (s *LocalCEPSuite) TestExact() {
+	addr, err := s.open().GetAddress(context.Background(), "01001-000")
+
+	s.NoError(err)
+	s.Equal(domain.Address{
+		PostalCode:   "01001000",
+		Street:       "Praça da Sé",
+		Neighborhood: "Sé",
+		City:         "São Paulo",
+		UF:           "SP",
+		State:        "São Paulo",
+		Region:       "Sudeste",
+		IBGE:         "3550308",
+		DDD:          "11",
+		Source:       "localcep",
+	}, addr)
+}
+
+func (s *LocalCEPSuite) TestRange() {
+	l := s.open()
+
+	for _, cep := range []string{"78890000", "78890-500", "78899999"} {
+		addr, err := l.GetAddress(context.Background(), cep)
+
+		s.NoError(err, cep)
+		s.Equal("Sorriso", addr.City, cep)
+		s.Equal(domain.NormalizePostalCode(cep), addr.PostalCode)
+	}
+}
+
+func (s *LocalCEPSuite) TestNestedRange() {
+	l := s.open()
+
+	addr, err := l.GetAddress(context.Background(), "78895100")
+	s.NoError(err)
+	s.Equal("Distrito de Boa Esperança", addr.City)
+
+	addr, err = l.GetAddress(context.Background(), "78896000")
+	s.NoError(err)
+	s.Equal("Sorriso", addr.City)
+}
+
+func (s *LocalCEPSuite) TestOverlappingRanges() {
+	var b strings.Builder
+	b.WriteString("cep,localidade,uf,cep_fim\n")
+	b.WriteString("10000-000,Outer,SP,19999-999\n")
+	b.WriteString("11000-000,Inner,SP,11999-999\n")
+	b.WriteString("11500-000,Innermost,SP,11500-999\n")
+	b.WriteString("19000-000,Overlap,SP,29999-999\n")
+	b.WriteString("30000-000,Alone,SP,30000-099\n")
+	// siblings before a CEP of the range around them
+	for i := range 1000 {
+		fmt.Fprintf(&b, "%08d,Sibling,SP,%08d\n", 13000000+i*100, 13000000+i*100+49)
+	}
+
+	path := filepath.Join(s.T().TempDir(), "overlapping.db")
+	_, err := localcep.Import(context.Background(), path, strings.NewReader(b.String()))
+	s.Require().NoError(err)
+
+	l, err := localcep.Open(path)
+	s.Require().NoError(err)
+	defer l.Close()
+
+	for cep, city := range map[string]string{
+		"10000000": "Outer",
+		"11000000": "Inner",
+		"11500500": "Innermost",
+		"11501000": "Inner",
+		"12000000": "Outer",
+		"13000049": "Sibling",
+		"13000050": "Outer",
+		"13099950": "Outer",
+		"19500000": "Overlap",
+		"25000000": "Overlap",
+		"30000099": "Alone",
+	} {
+		addr, err := l.GetAddress(context.Background(), cep)
+		s.NoError(err, cep)
+		s.Equal(city, addr.City, cep)
+	}
+
+	for _, cep := range []string{"09999999", "30000100", "99999999"} {
+		_, err := l.GetAddress(context.Background(), cep)
+		s.ErrorIs(err, domain.ErrPostalCodeNotFound, cep)
+	}
+}
+
+func (s *LocalCEPSuite) TestPostalCodeNotFound() {
+	l := s.open()
+
+	for _, cep := range []string{"01001001", "00000000", "99999999", "123"} {
+		_, err := l.GetAddress(context.Background(), cep)
+
+		s.ErrorIs(err, domain.ErrPostalCodeNotFound, cep)
+	}
+}
+
+func (s *LocalCEPSuite) TestRefresh() {
+	l := s.open()
+
+	_, err := localcep.Import(context.Background(), s.path, strings.NewReader(
+		"cep,localidade,uf\n01310-100,São Paulo,SP\n",
+	))
+	s.NoError(err)
+
+	// the open index is unaffected until reopened
+	_, err = l.GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+
+	l = s.open()
+	_, err = l.GetAddress(context.Background(), "01001000")
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	addr, err := l.GetAddress(context.Background(), "01310100")
+	s.NoError(err)
+	s.Equal("São Paulo", addr.City)
+}
+
+func (s *LocalCEPSuite) TestFailedImportKeepsIndex() {
+	_, err := localcep.Import(context.Background(), s.path, strings.NewReader(
+		"cep,localidade,uf\n01310-100,São Paulo,SP\nabc,Nowhere,XX\n",
+	))
+	s.Error(err)
+
+	_, err = os.Stat(s.path + ".tmp")
+	s.ErrorIs(err, os.ErrNotExist)
+
+	_, err = s.open().GetAddress(context.Background(), "01001000")
+	s.NoError(err)
+}
+
+func (s *LocalCEPSuite) TestMissingColumn() {
+	_, err := localcep.Import(context.Background(), s.path, strings.NewReader("cep,uf\n01001000,SP\n"))
+
+	s.ErrorContains(err, `missing column "localidade"`)
+}
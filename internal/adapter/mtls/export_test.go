@@ -0,0 +1,10 @@
+package mtls
+
+import "time"
+
+// SetCheckInterval makes c check its files every d, starting on the next
+// handshake. It must be called before c is shared.
+func (c *Certificate) SetCheckInterval(d time.Duration) {
+	c.checkInterval = d
+	c.nextCheck.Store(0)
+}
@@ -0,0 +1,189 @@
+// Package mtls secures the calls from service A to service B: both serve
+// HTTPS with certificates read from files, which are read again when they
+// are rotated, and service B may require service A to present a certificate
+// of a trusted CA with an allowed subject.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClientNotAllowed is returned by the handshake of clients whose
+// certificate subject is not allowed.
+var ErrClientNotAllowed = errors.New("client certificate subject not allowed")
+
+// checkInterval is how often the files of a [Certificate] are checked for
+// changes.
+const checkInterval = 10 * time.Second
+
+// Certificate is a key pair read from PEM files and read again once their
+// modification times change, which a handshake checks at most once every 10
+// seconds. A pair that fails to load, such as one caught midway through a
+// rotation, is ignored in favour of the current one.
+type Certificate struct {
+	certFile, keyFile string
+	checkInterval     time.Duration
+
+	cert atomic.Pointer[tls.Certificate]
+	// nextCheck is when, in Unix nanoseconds, the files are checked again
+	nextCheck atomic.Int64
+
+	// mu is held by the handshake checking the files; modTimes are those
+	// of the loaded pair
+	mu       sync.Mutex
+	modTimes [2]time.Time
+}
+
+// LoadCertificate returns the [Certificate] in certFile and keyFile.
+func LoadCertificate(certFile, keyFile string) (*Certificate, error) {
+	c := &Certificate{certFile: certFile, keyFile: keyFile, checkInterval: checkInterval}
+
+	modTimes, err := c.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.load(modTimes); err != nil {
+		return nil, err
+	}
+	c.nextCheck.Store(time.Now().Add(c.checkInterval).UnixNano())
+
+	return c, nil
+}
+
+// GetCertificate returns the current certificate. It fits
+// [tls.Config.GetCertificate].
+func (c *Certificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.current(), nil
+}
+
+// GetClientCertificate returns the current certificate. It fits
+// [tls.Config.GetClientCertificate].
+func (c *Certificate) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return c.current(), nil
+}
+
+// current returns the current certificate. Once the check interval passes,
+// the handshake that gets there first checks the files while the others go
+// on with the current certificate.
+func (c *Certificate) current() *tls.Certificate {
+	if now := time.Now(); now.UnixNano() >= c.nextCheck.Load() && c.mu.TryLock() {
+		c.nextCheck.Store(now.Add(c.checkInterval).UnixNano())
+		c.check()
+		c.mu.Unlock()
+	}
+
+	return c.cert.Load()
+}
+
+// check reads the key pair again if its files changed, to be called with c.mu
+// held.
+func (c *Certificate) check() {
+	modTimes, err := c.stat()
+	if err != nil || modTimes == c.modTimes {
+		return
+	}
+
+	if err := c.load(modTimes); err != nil {
+		log.Println("certificate reload error, keeping the current one:", err)
+	}
+}
+
+// load reads the key pair, to be called with c.mu held or before c is
+// shared.
+func (c *Certificate) load(modTimes [2]time.Time) error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading key pair: %w", err)
+	}
+
+	c.cert.Store(&cert)
+	c.modTimes = modTimes
+
+	return nil
+}
+
+// stat returns the modification times of the files of c.
+func (c *Certificate) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, fmt.Errorf("loading key pair: %w", err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// LoadCAs returns a pool of the CAs in the PEM files at caFiles only, without
+// those of the system.
+func LoadCAs(caFiles []string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	for _, path := range caFiles {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", path)
+		}
+	}
+
+	return pool, nil
+}
+
+// ServerConfig returns the configuration of a server presenting cert. When
+// clientCAs is set, clients must present a certificate issued by one of
+// them and, when allowed is set too, whose subject is in allowed, either as
+// its common name or as its whole distinguished name, such as
+// "CN=service-a,O=weather".
+func ServerConfig(cert *Certificate, clientCAs *x509.CertPool, allowed []string) *tls.Config {
+	c := &tls.Config{GetCertificate: cert.GetCertificate, MinVersion: tls.VersionTLS12}
+	if clientCAs == nil {
+		return c
+	}
+
+	c.ClientCAs = clientCAs
+	c.ClientAuth = tls.RequireAndVerifyClientCert
+	if len(allowed) == 0 {
+		return c
+	}
+
+	c.VerifyConnection = func(cs tls.ConnectionState) error {
+		subject := cs.PeerCertificates[0].Subject
+		if slices.Contains(allowed, subject.CommonName) || slices.Contains(allowed, subject.String()) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrClientNotAllowed, subject)
+	}
+
+	return c
+}
+
+// Authenticated reports whether r came from a client whose certificate was
+// verified by a server configured by [ServerConfig].
+func Authenticated(r *http.Request) bool {
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
+// ClientConfig returns the configuration of a client trusting roots, or the
+// CAs of the system when nil, and presenting cert, if set.
+func ClientConfig(cert *Certificate, roots *x509.CertPool) *tls.Config {
+	c := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	if cert != nil {
+		c.GetClientCertificate = cert.GetClientCertificate
+	}
+
+	return c
+}
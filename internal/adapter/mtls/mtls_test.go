@@ -0,0 +1,279 @@
+package mtls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/mtls"
+)
+
+// testCA issues certificates for the tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA() (*testCA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &testCA{cert: cert, key: key}, nil
+}
+
+// issue returns the PEM certificate and key of subject.
+func (ca *testCA) issue(subject pkix.Name, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
+
+type MTLSSuite struct {
+	suite.Suite
+	ca  *testCA
+	dir string
+}
+
+func TestMTLSSuite(t *testing.T) {
+	suite.Run(t, new(MTLSSuite))
+}
+
+func (s *MTLSSuite) SetupTest() {
+	var err error
+	s.ca, err = newTestCA()
+	s.Require().NoError(err)
+
+	s.dir = s.T().TempDir()
+	s.write("ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.ca.cert.Raw}))
+}
+
+func (s *MTLSSuite) write(name string, b []byte) string {
+	path := filepath.Join(s.dir, name)
+	s.Require().NoError(os.WriteFile(path, b, 0o600))
+	return path
+}
+
+// certificate issues a certificate of subject into files named after name.
+func (s *MTLSSuite) certificate(name string, subject pkix.Name, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	certPEM, keyPEM, err := s.ca.issue(subject, usage)
+	s.Require().NoError(err)
+
+	return s.write(name+".pem", certPEM), s.write(name+"-key.pem", keyPEM)
+}
+
+func (s *MTLSSuite) loadCertificate(certFile, keyFile string) *mtls.Certificate {
+	cert, err := mtls.LoadCertificate(certFile, keyFile)
+	s.Require().NoError(err)
+	return cert
+}
+
+// serve starts an HTTPS server with config answering with the common name of
+// the client certificate, if authenticated.
+func (s *MTLSSuite) serve(config *tls.Config) string {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mtls.Authenticated(r) {
+			_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	srv.Listener = tls.NewListener(srv.Listener, config)
+	srv.Start()
+	s.T().Cleanup(srv.Close)
+
+	return strings.Replace(srv.URL, "http://", "https://", 1)
+}
+
+// get calls url on a new connection, returning the common names of the
+// server and of the client, as seen by the server.
+func (s *MTLSSuite) get(url string, config *tls.Config) (server, client string, err error) {
+	cl := &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+
+	res, err := cl.Get(url)
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+
+	return res.TLS.PeerCertificates[0].Subject.CommonName, string(b), err
+}
+
+func (s *MTLSSuite) cas() *x509.CertPool {
+	pool, err := mtls.LoadCAs([]string{filepath.Join(s.dir, "ca.pem")})
+	s.Require().NoError(err)
+	return pool
+}
+
+func (s *MTLSSuite) TestServerOnly() {
+	cert := s.loadCertificate(s.certificate("server", pkix.Name{CommonName: "service-b"}, x509.ExtKeyUsageServerAuth))
+	url := s.serve(mtls.ServerConfig(cert, nil, nil))
+
+	server, client, err := s.get(url, mtls.ClientConfig(nil, s.cas()))
+	s.Require().NoError(err)
+	s.Equal("service-b", server)
+	s.Empty(client)
+
+	_, _, err = s.get(url, mtls.ClientConfig(nil, x509.NewCertPool()))
+	s.Error(err)
+}
+
+func (s *MTLSSuite) TestMutual() {
+	cert := s.loadCertificate(s.certificate("server", pkix.Name{CommonName: "service-b"}, x509.ExtKeyUsageServerAuth))
+	url := s.serve(mtls.ServerConfig(cert, s.cas(), nil))
+
+	clientCert := s.loadCertificate(s.certificate("client", pkix.Name{CommonName: "service-a"}, x509.ExtKeyUsageClientAuth))
+	_, client, err := s.get(url, mtls.ClientConfig(clientCert, s.cas()))
+	s.Require().NoError(err)
+	s.Equal("service-a", client)
+
+	_, _, err = s.get(url, mtls.ClientConfig(nil, s.cas()))
+	s.Error(err)
+}
+
+func (s *MTLSSuite) TestAllowedSubjects() {
+	cert := s.loadCertificate(s.certificate("server", pkix.Name{CommonName: "service-b"}, x509.ExtKeyUsageServerAuth))
+	url := s.serve(mtls.ServerConfig(cert, s.cas(), []string{"service-a", "CN=service-c,O=weather"}))
+
+	for _, subject := range []pkix.Name{
+		{CommonName: "service-a"},
+		{CommonName: "service-c", Organization: []string{"weather"}},
+	} {
+		clientCert := s.loadCertificate(s.certificate("client", subject, x509.ExtKeyUsageClientAuth))
+		_, client, err := s.get(url, mtls.ClientConfig(clientCert, s.cas()))
+		s.Require().NoError(err)
+		s.Equal(subject.CommonName, client)
+	}
+
+	intruder := s.loadCertificate(s.certificate("intruder", pkix.Name{CommonName: "intruder"}, x509.ExtKeyUsageClientAuth))
+	_, _, err := s.get(url, mtls.ClientConfig(intruder, s.cas()))
+	s.Error(err)
+}
+
+// rotating returns the certificate in certFile and keyFile, checking them
+// for changes every interval.
+func (s *MTLSSuite) rotating(certFile, keyFile string, interval time.Duration) *mtls.Certificate {
+	cert := s.loadCertificate(certFile, keyFile)
+	cert.SetCheckInterval(interval)
+	return cert
+}
+
+// rotate replaces the certificate in certFile and keyFile by one of subject.
+func (s *MTLSSuite) rotate(subject pkix.Name) {
+	certFile, keyFile := s.certificate("server", subject, x509.ExtKeyUsageServerAuth)
+	later := time.Now().Add(time.Minute)
+	s.Require().NoError(os.Chtimes(certFile, later, later))
+	s.Require().NoError(os.Chtimes(keyFile, later, later))
+}
+
+func (s *MTLSSuite) TestRotation() {
+	certFile, keyFile := s.certificate("server", pkix.Name{CommonName: "old"}, x509.ExtKeyUsageServerAuth)
+	url := s.serve(mtls.ServerConfig(s.rotating(certFile, keyFile, 0), nil, nil))
+
+	server, _, err := s.get(url, mtls.ClientConfig(nil, s.cas()))
+	s.Require().NoError(err)
+	s.Equal("old", server)
+
+	s.rotate(pkix.Name{CommonName: "new"})
+
+	server, _, err = s.get(url, mtls.ClientConfig(nil, s.cas()))
+	s.Require().NoError(err)
+	s.Equal("new", server)
+}
+
+func (s *MTLSSuite) TestRotationIsCheckedOncePerInterval() {
+	certFile, keyFile := s.certificate("server", pkix.Name{CommonName: "old"}, x509.ExtKeyUsageServerAuth)
+	url := s.serve(mtls.ServerConfig(s.rotating(certFile, keyFile, time.Hour), nil, nil))
+
+	server, _, err := s.get(url, mtls.ClientConfig(nil, s.cas()))
+	s.Require().NoError(err)
+	s.Equal("old", server)
+
+	s.rotate(pkix.Name{CommonName: "new"})
+
+	server, _, err = s.get(url, mtls.ClientConfig(nil, s.cas()))
+	s.Require().NoError(err)
+	s.Equal("old", server)
+}
+
+func (s *MTLSSuite) TestBrokenRotationKeepsCertificate() {
+	certFile, keyFile := s.certificate("server", pkix.Name{CommonName: "old"}, x509.ExtKeyUsageServerAuth)
+	url := s.serve(mtls.ServerConfig(s.rotating(certFile, keyFile, 0), nil, nil))
+
+	// only the certificate is replaced, so it no longer matches the key
+	certPEM, _, err := s.ca.issue(pkix.Name{CommonName: "new"}, x509.ExtKeyUsageServerAuth)
+	s.Require().NoError(err)
+	s.write("server.pem", certPEM)
+	later := time.Now().Add(time.Minute)
+	s.Require().NoError(os.Chtimes(certFile, later, later))
+
+	server, _, err := s.get(url, mtls.ClientConfig(nil, s.cas()))
+	s.Require().NoError(err)
+	s.Equal("old", server)
+}
+
+func (s *MTLSSuite) TestLoadErrors() {
+	_, err := mtls.LoadCertificate(filepath.Join(s.dir, "missing.pem"), filepath.Join(s.dir, "missing-key.pem"))
+	s.ErrorIs(err, os.ErrNotExist)
+
+	_, err = mtls.LoadCAs([]string{s.write("empty.pem", []byte("not a certificate"))})
+	s.ErrorContains(err, "no certificates found")
+}
@@ -0,0 +1,110 @@
+// Package opencep implements [domain.AddressGetter] using OpenCEP.
+package opencep
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+)
+
+const baseURL = "https://opencep.com/v1/"
+
+// ErrStatusCode is returned when OpenCEP answers with an unexpected status.
+type ErrStatusCode struct {
+	Status int
+}
+
+// Error implements [error].
+func (e ErrStatusCode) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.Status)
+}
+
+// OpenCEP is a [domain.AddressGetter] backed by OpenCEP.
+type OpenCEP struct {
+	cl *http.Client
+}
+
+// NewAddressGetter returns a new implementation of [domain.AddressGetter].
+func NewAddressGetter(cl *http.Client) domain.AddressGetter {
+	return &OpenCEP{cl: cl}
+}
+
+// GetAddress implements [domain.AddressGetter].
+func (a *OpenCEP) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "get-address")
+	defer span.End()
+
+	u, err := url.JoinPath(baseURL, postalCode)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("mounting url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := a.cl.Do(req)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("doing request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return domain.Address{}, domain.ErrPostalCodeNotFound
+	default:
+		return domain.Address{}, ErrStatusCode{Status: res.StatusCode}
+	}
+
+	var body openCEP
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return domain.Address{}, fmt.Errorf("decoding response: %w", err)
+	}
+
+	if body.Error != "" {
+		return domain.Address{}, domain.ErrPostalCodeNotFound
+	}
+
+	return body.toAddress(postalCode), nil
+}
+
+type openCEP struct {
+	Error       string `json:"error"`
+	Cep         string `json:"cep"`
+	Logradouro  string `json:"logradouro"`
+	Complemento string `json:"complemento"`
+	Unidade     string `json:"unidade"`
+	Bairro      string `json:"bairro"`
+	Localidade  string `json:"localidade"`
+	Uf          string `json:"uf"`
+	Estado      string `json:"estado"`
+	Regiao      string `json:"regiao"`
+	Ibge        string `json:"ibge"`
+}
+
+func (o openCEP) toAddress(postalCode string) domain.Address {
+	cep := domain.NormalizePostalCode(o.Cep)
+	if cep == "" {
+		cep = domain.NormalizePostalCode(postalCode)
+	}
+
+	return domain.Address{
+		PostalCode:   cep,
+		Street:       o.Logradouro,
+		Neighborhood: o.Bairro,
+		City:         o.Localidade,
+		UF:           o.Uf,
+		State:        o.Estado,
+		Region:       o.Regiao,
+		IBGE:         o.Ibge,
+		Source:       "opencep",
+	}
+}
@@ -0,0 +1,135 @@
+package opencep_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/opencep"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type OpenCEPSuite struct {
+	suite.Suite
+}
+
+func TestOpenCEPSuite(t *testing.T) {
+	suite.Run(t, new(OpenCEPSuite))
+}
+
+func (s *OpenCEPSuite) newGetter(rt roundTripperFunc) *http.Client {
+	return &http.Client{Transport: rt}
+}
+
+func (s *OpenCEPSuite) TestFailedRequest() {
+	expectedErr := errors.New("request failed")
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, expectedErr
+	})
+
+	ag := opencep.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorIs(err, expectedErr)
+	s.Empty(addr)
+}
+
+func (s *OpenCEPSuite) TestNonOKStatusCode() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+
+	ag := opencep.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorAs(err, &opencep.ErrStatusCode{})
+	s.Empty(addr)
+}
+
+func (s *OpenCEPSuite) TestPostalCodeNotFound() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader(`{"error":"CEP não encontrado"}`)),
+		}, nil
+	})
+
+	ag := opencep.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "99999999")
+
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Empty(addr)
+}
+
+func (s *OpenCEPSuite) TestInvalidJSON() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("{]")),
+		}, nil
+	})
+
+	ag := opencep.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.Error(err)
+	s.Empty(addr)
+}
+
+func (s *OpenCEPSuite) TestSuccessfulAddress() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		s.Equal("https://opencep.com/v1/01001000", r.URL.String())
+
+		body := `{"cep":"01001-000","logradouro":"Praça da Sé","bairro":"Sé",` +
+			`"localidade":"São Paulo","uf":"SP","estado":"São Paulo",` +
+			`"regiao":"Sudeste","ibge":"3550308"}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	ag := opencep.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal(domain.Address{
+		PostalCode:   "01001000",
+		Street:       "Praça da Sé",
+		Neighborhood: "Sé",
+		City:         "São Paulo",
+		UF:           "SP",
+		State:        "São Paulo",
+		Region:       "Sudeste",
+		IBGE:         "3550308",
+		Source:       "opencep",
+	}, addr)
+}
+
+func (s *OpenCEPSuite) TestErrorInBody() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"error":"CEP não encontrado"}`)),
+		}, nil
+	})
+
+	ag := opencep.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "99999999")
+
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Empty(addr)
+}
@@ -0,0 +1,252 @@
+// Package openmeteo implements [domain.TemperatureGetter] using Open-Meteo,
+// which needs no API key. Locations are resolved to coordinates with the
+// Open-Meteo geocoding API before the weather is requested.
+package openmeteo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	geocodingURL = "https://geocoding-api.open-meteo.com/v1/search"
+	forecastURL  = "https://api.open-meteo.com/v1/forecast"
+
+	currentVariables = "temperature_2m,apparent_temperature,relative_humidity_2m," +
+		"pressure_msl,wind_speed_10m,wind_direction_10m,uv_index,visibility," +
+		"cloud_cover,precipitation,weather_code"
+)
+
+var (
+	// ErrLocationNotFound is returned when the geocoding API has no match for
+	// the location. It is [domain.ErrLocationNotFound].
+	ErrLocationNotFound = domain.ErrLocationNotFound
+	// ErrNoTemperature is returned when the response has no current
+	// temperature.
+	ErrNoTemperature = errors.New("no current temperature")
+)
+
+// ErrStatusCode is returned when Open-Meteo answers with an unexpected status.
+// Reason carries the reason from the response body, when any.
+type ErrStatusCode struct {
+	Status int
+	Reason string
+}
+
+// Error implements [error].
+func (e ErrStatusCode) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("unexpected status code %d", e.Status)
+	}
+	return fmt.Sprintf("unexpected status code %d: %s", e.Status, e.Reason)
+}
+
+// OpenMeteo is a [domain.TemperatureGetter] backed by Open-Meteo.
+type OpenMeteo struct {
+	cl *http.Client
+}
+
+// NewTemperatureGetter returns a new implementation of
+// [domain.TemperatureGetter].
+func NewTemperatureGetter(cl *http.Client) domain.TemperatureGetter {
+	return &OpenMeteo{cl: cl}
+}
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (o *OpenMeteo) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := o.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter].
+func (o *OpenMeteo) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "get-temperature")
+	defer span.End()
+
+	coords, err := o.coordinates(ctx, location)
+	if err != nil {
+		return domain.Observation{}, err
+	}
+
+	q := url.Values{
+		"latitude":        {strconv.FormatFloat(coords.Latitude, 'f', -1, 64)},
+		"longitude":       {strconv.FormatFloat(coords.Longitude, 'f', -1, 64)},
+		"current":         {currentVariables},
+		"timezone":        {"UTC"},
+		"wind_speed_unit": {"kmh"},
+	}
+
+	var body forecast
+	if err := o.get(ctx, forecastURL, q, &body); err != nil {
+		return domain.Observation{}, err
+	}
+
+	return body.Current.toObservation()
+}
+
+// coordinates returns the coordinates of location, asking the geocoding API
+// only when they are not already known. Cities share names across states,
+// so the result must be in the state of the location, when it has one.
+func (o *OpenMeteo) coordinates(ctx context.Context, location domain.Location) (domain.Coordinates, error) {
+	if location.Coordinates != nil {
+		return *location.Coordinates, nil
+	}
+
+	q := url.Values{
+		"name":        {location.City},
+		"count":       {"10"},
+		"language":    {"pt"},
+		"countryCode": {"BR"},
+		"format":      {"json"},
+	}
+
+	var body geocoding
+	if err := o.get(ctx, geocodingURL, q, &body); err != nil {
+		return domain.Coordinates{}, err
+	}
+
+	for _, r := range body.Results {
+		if location.UF == "" || domain.InState(r.Admin1, location.UF) {
+			return domain.Coordinates{Latitude: r.Latitude, Longitude: r.Longitude}, nil
+		}
+	}
+
+	return domain.Coordinates{}, ErrLocationNotFound
+}
+
+func (o *OpenMeteo) get(ctx context.Context, base string, q url.Values, v any) error {
+	u, err := url.Parse(base)
+	if err != nil {
+		return fmt.Errorf("parsing URL: %w", err)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := o.cl.Do(req)
+	if err != nil {
+		return fmt.Errorf("doing request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var body errorBody
+		_ = json.NewDecoder(res.Body).Decode(&body)
+		return ErrStatusCode{Status: res.StatusCode, Reason: body.Reason}
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+type errorBody struct {
+	Error  bool   `json:"error"`
+	Reason string `json:"reason"`
+}
+
+type geocoding struct {
+	Results []struct {
+		Name        string  `json:"name"`
+		Latitude    float64 `json:"latitude"`
+		Longitude   float64 `json:"longitude"`
+		CountryCode string  `json:"country_code"`
+		Admin1      string  `json:"admin1"`
+	} `json:"results"`
+}
+
+type forecast struct {
+	Current current `json:"current"`
+}
+
+type current struct {
+	Time                string   `json:"time"`
+	Temperature2M       *float64 `json:"temperature_2m"`
+	ApparentTemperature float64  `json:"apparent_temperature"`
+	RelativeHumidity2M  float64  `json:"relative_humidity_2m"`
+	PressureMsl         float64  `json:"pressure_msl"`
+	WindSpeed10M        float64  `json:"wind_speed_10m"`
+	WindDirection10M    float64  `json:"wind_direction_10m"`
+	UvIndex             float64  `json:"uv_index"`
+	Visibility          float64  `json:"visibility"`
+	CloudCover          float64  `json:"cloud_cover"`
+	Precipitation       float64  `json:"precipitation"`
+	WeatherCode         int      `json:"weather_code"`
+}
+
+func (c current) toObservation() (domain.Observation, error) {
+	if c.Temperature2M == nil {
+		return domain.Observation{}, ErrNoTemperature
+	}
+
+	// timezone=UTC is requested, so the ISO8601 time has no offset
+	observedAt, _ := time.Parse("2006-01-02T15:04", c.Time)
+
+	return domain.Observation{
+		TempC:         *c.Temperature2M,
+		FeelsLikeC:    c.ApparentTemperature,
+		Humidity:      c.RelativeHumidity2M,
+		PressureHPa:   c.PressureMsl,
+		WindSpeedKmph: c.WindSpeed10M,
+		WindDirDegree: c.WindDirection10M,
+		UVIndex:       c.UvIndex,
+		VisibilityKm:  c.Visibility / 1000,
+		CloudCover:    c.CloudCover,
+		PrecipMM:      c.Precipitation,
+		Description:   weatherCodes[c.WeatherCode],
+		ObservedAt:    observedAt,
+		Source:        "open-meteo",
+	}, nil
+}
+
+// weatherCodes describes the WMO weather interpretation codes returned by
+// Open-Meteo.
+var weatherCodes = map[int]string{
+	0:  "Céu limpo",
+	1:  "Predominantemente limpo",
+	2:  "Parcialmente nublado",
+	3:  "Encoberto",
+	45: "Nevoeiro",
+	48: "Nevoeiro com geada",
+	51: "Garoa fraca",
+	53: "Garoa moderada",
+	55: "Garoa intensa",
+	56: "Garoa congelante fraca",
+	57: "Garoa congelante intensa",
+	61: "Chuva fraca",
+	63: "Chuva moderada",
+	65: "Chuva forte",
+	66: "Chuva congelante fraca",
+	67: "Chuva congelante forte",
+	71: "Neve fraca",
+	73: "Neve moderada",
+	75: "Neve forte",
+	77: "Grãos de neve",
+	80: "Pancadas de chuva fracas",
+	81: "Pancadas de chuva moderadas",
+	82: "Pancadas de chuva violentas",
+	85: "Pancadas de neve fracas",
+	86: "Pancadas de neve fortes",
+	95: "Trovoada",
+	96: "Trovoada com granizo fraco",
+	99: "Trovoada com granizo forte",
+}
@@ -0,0 +1,196 @@
+package openmeteo_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/openmeteo"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+const geocodingBody = `{"results":[{"name":"São Paulo","latitude":-23.5475,` +
+	`"longitude":-46.63611,"country_code":"BR","admin1":"São Paulo"}]}`
+
+type OpenMeteoSuite struct {
+	suite.Suite
+}
+
+func TestOpenMeteoSuite(t *testing.T) {
+	suite.Run(t, new(OpenMeteoSuite))
+}
+
+func (s *OpenMeteoSuite) newGetter(rt roundTripperFunc) *http.Client {
+	return &http.Client{Transport: rt}
+}
+
+func (s *OpenMeteoSuite) respond(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func (s *OpenMeteoSuite) TestFailedRequest() {
+	expectedErr := errors.New("request failed")
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, expectedErr
+	})
+
+	tg := openmeteo.NewTemperatureGetter(s.newGetter(rt))
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.ErrorIs(err, expectedErr)
+	s.Zero(temp)
+}
+
+func (s *OpenMeteoSuite) TestNonOKStatusCode() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "geocoding-api.open-meteo.com" {
+			return s.respond(http.StatusOK, geocodingBody), nil
+		}
+		return s.respond(http.StatusBadRequest, `{"error":true,"reason":"Cannot initialize WeatherVariable"}`), nil
+	})
+
+	tg := openmeteo.NewTemperatureGetter(s.newGetter(rt))
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	var statusErr openmeteo.ErrStatusCode
+	s.ErrorAs(err, &statusErr)
+	s.Equal(http.StatusBadRequest, statusErr.Status)
+	s.Equal("Cannot initialize WeatherVariable", statusErr.Reason)
+	s.Zero(temp)
+}
+
+func (s *OpenMeteoSuite) TestLocationNotFound() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return s.respond(http.StatusOK, `{"generationtime_ms":0.5}`), nil
+	})
+
+	tg := openmeteo.NewTemperatureGetter(s.newGetter(rt))
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "Nowhere"})
+
+	s.ErrorIs(err, openmeteo.ErrLocationNotFound)
+	s.Zero(temp)
+}
+
+func (s *OpenMeteoSuite) TestInvalidJSON() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return s.respond(http.StatusOK, "{]"), nil
+	})
+
+	tg := openmeteo.NewTemperatureGetter(s.newGetter(rt))
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.Error(err)
+	s.Zero(temp)
+}
+
+func (s *OpenMeteoSuite) TestNoTemperature() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "geocoding-api.open-meteo.com" {
+			return s.respond(http.StatusOK, geocodingBody), nil
+		}
+		return s.respond(http.StatusOK, `{"current":{}}`), nil
+	})
+
+	tg := openmeteo.NewTemperatureGetter(s.newGetter(rt))
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.ErrorIs(err, openmeteo.ErrNoTemperature)
+	s.Zero(temp)
+}
+
+func (s *OpenMeteoSuite) TestSuccessfulObservation() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "geocoding-api.open-meteo.com" {
+			s.Equal("São Paulo", r.URL.Query().Get("name"))
+			return s.respond(http.StatusOK, geocodingBody), nil
+		}
+
+		s.Equal("-23.5475", r.URL.Query().Get("latitude"))
+		s.Equal("-46.63611", r.URL.Query().Get("longitude"))
+
+		body := `{"current":{"time":"2025-01-15T14:00","temperature_2m":25.5,` +
+			`"apparent_temperature":27.1,"relative_humidity_2m":60,` +
+			`"pressure_msl":1015.2,"wind_speed_10m":11.2,"wind_direction_10m":120,` +
+			`"uv_index":6.5,"visibility":24140,"cloud_cover":25,` +
+			`"precipitation":0.1,"weather_code":2}}`
+		return s.respond(http.StatusOK, body), nil
+	})
+
+	og := openmeteo.NewTemperatureGetter(s.newGetter(rt)).(domain.ObservationGetter)
+	obs, err := og.GetObservation(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.NoError(err)
+	s.Equal(domain.Observation{
+		TempC:         25.5,
+		FeelsLikeC:    27.1,
+		Humidity:      60,
+		PressureHPa:   1015.2,
+		WindSpeedKmph: 11.2,
+		WindDirDegree: 120,
+		UVIndex:       6.5,
+		VisibilityKm:  24.14,
+		CloudCover:    25,
+		PrecipMM:      0.1,
+		Description:   "Parcialmente nublado",
+		ObservedAt:    time.Date(2025, 1, 15, 14, 0, 0, 0, time.UTC),
+		Source:        "open-meteo",
+	}, obs)
+}
+
+func (s *OpenMeteoSuite) TestCoordinatesSkipGeocoding() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		s.NotEqual("geocoding-api.open-meteo.com", r.URL.Host)
+		s.Equal("-9.07", r.URL.Query().Get("latitude"))
+		s.Equal("-44.36", r.URL.Query().Get("longitude"))
+
+		return s.respond(http.StatusOK, `{"current":{"temperature_2m":30}}`), nil
+	})
+
+	tg := openmeteo.NewTemperatureGetter(s.newGetter(rt))
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{
+		City:        "Bom Jesus",
+		Coordinates: &domain.Coordinates{Latitude: -9.07, Longitude: -44.36},
+	})
+
+	s.NoError(err)
+	s.Equal(30.0, temp)
+}
+
+func (s *OpenMeteoSuite) TestGeocodingMatchesUF() {
+	geocoding := `{"results":[` +
+		`{"name":"Bom Jesus","latitude":-28.67,"longitude":-50.43,"country_code":"BR","admin1":"Rio Grande do Sul"},` +
+		`{"name":"Bom Jesus","latitude":-9.07,"longitude":-44.36,"country_code":"BR","admin1":"Piauí"}]}`
+
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "geocoding-api.open-meteo.com" {
+			return s.respond(http.StatusOK, geocoding), nil
+		}
+		s.Equal("-9.07", r.URL.Query().Get("latitude"))
+		s.Equal("-44.36", r.URL.Query().Get("longitude"))
+
+		return s.respond(http.StatusOK, `{"current":{"temperature_2m":30}}`), nil
+	})
+
+	tg := openmeteo.NewTemperatureGetter(s.newGetter(rt))
+
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "Bom Jesus", UF: "PI"})
+	s.NoError(err)
+	s.Equal(30.0, temp)
+
+	_, err = tg.GetTemperature(context.Background(), domain.Location{City: "Bom Jesus", UF: "SC"})
+	s.ErrorIs(err, openmeteo.ErrLocationNotFound)
+}
@@ -0,0 +1,233 @@
+// Package openweathermap implements [domain.TemperatureGetter] using the
+// OpenWeatherMap current weather API.
+package openweathermap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	baseURL      = "https://api.openweathermap.org/data/2.5/weather"
+	geocodingURL = "https://api.openweathermap.org/geo/1.0/direct"
+)
+
+// ErrLocationNotFound is returned when OpenWeatherMap has no match for the
+// location. It is [domain.ErrLocationNotFound].
+var ErrLocationNotFound = domain.ErrLocationNotFound
+
+// ErrAPI is returned when OpenWeatherMap answers with an unexpected status,
+// e.g. 401 for an invalid key or 429 for an exceeded quota.
+type ErrAPI struct {
+	Status  int
+	Message string
+}
+
+// Error implements [error].
+func (e ErrAPI) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status code %d", e.Status)
+	}
+	return fmt.Sprintf("unexpected status code %d: %s", e.Status, e.Message)
+}
+
+// OpenWeatherMap is a [domain.TemperatureGetter] backed by OpenWeatherMap.
+type OpenWeatherMap struct {
+	cl  *http.Client
+	key string
+}
+
+// NewTemperatureGetter returns a new implementation of
+// [domain.TemperatureGetter] authenticated with apiKey.
+func NewTemperatureGetter(cl *http.Client, apiKey string) domain.TemperatureGetter {
+	return &OpenWeatherMap{cl: cl, key: apiKey}
+}
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (o *OpenWeatherMap) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := o.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter]. The weather API
+// ignores the state of cities outside the US, so a location with a UF but no
+// coordinates is geocoded first, within its state.
+func (o *OpenWeatherMap) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "get-temperature")
+	defer span.End()
+
+	if location.Coordinates == nil && location.UF != "" {
+		coords, err := o.geocode(ctx, location)
+		if err != nil {
+			return domain.Observation{}, err
+		}
+		location.Coordinates = &coords
+	}
+
+	u, err := o.getURL(location)
+	if err != nil {
+		return domain.Observation{}, fmt.Errorf("mounting url: %w", err)
+	}
+
+	var body openWeatherMap
+	if err := o.get(ctx, u, &body); err != nil {
+		return domain.Observation{}, err
+	}
+
+	return body.toObservation(), nil
+}
+
+// geocode returns the coordinates of the city of location in its state.
+func (o *OpenWeatherMap) geocode(ctx context.Context, location domain.Location) (domain.Coordinates, error) {
+	u, err := url.Parse(geocodingURL)
+	if err != nil {
+		return domain.Coordinates{}, fmt.Errorf("parsing URL: %w", err)
+	}
+	u.RawQuery = url.Values{
+		"appid": {o.key},
+		"q":     {location.City + ",BR"},
+		"limit": {"5"},
+	}.Encode()
+
+	var places []struct {
+		Lat   float64 `json:"lat"`
+		Lon   float64 `json:"lon"`
+		State string  `json:"state"`
+	}
+	if err := o.get(ctx, u.String(), &places); err != nil {
+		return domain.Coordinates{}, err
+	}
+
+	for _, p := range places {
+		if domain.InState(p.State, location.UF) {
+			return domain.Coordinates{Latitude: p.Lat, Longitude: p.Lon}, nil
+		}
+	}
+
+	return domain.Coordinates{}, ErrLocationNotFound
+}
+
+// get decodes the response to u into v.
+func (o *OpenWeatherMap) get(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := o.cl.Do(req)
+	if err != nil {
+		return fmt.Errorf("doing request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrLocationNotFound
+	default:
+		var body errorBody
+		_ = json.NewDecoder(res.Body).Decode(&body)
+		return ErrAPI{Status: res.StatusCode, Message: body.Message}
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+func (o *OpenWeatherMap) getURL(location domain.Location) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing URL: %w", err)
+	}
+
+	q := url.Values{
+		"appid": {o.key},
+		"units": {"metric"},
+		"lang":  {"pt_br"},
+	}
+
+	if c := location.Coordinates; c != nil {
+		q.Set("lat", strconv.FormatFloat(c.Latitude, 'f', -1, 64))
+		q.Set("lon", strconv.FormatFloat(c.Longitude, 'f', -1, 64))
+	} else {
+		q.Set("q", location.City+",BR")
+	}
+
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// errorBody is the error payload. "cod" is sometimes a string and sometimes a
+// number, so only the message is decoded.
+type errorBody struct {
+	Message string `json:"message"`
+}
+
+type openWeatherMap struct {
+	Weather []struct {
+		Description string `json:"description"`
+	} `json:"weather"`
+	Main struct {
+		Temp      float64 `json:"temp"`
+		FeelsLike float64 `json:"feels_like"`
+		Pressure  float64 `json:"pressure"`
+		Humidity  float64 `json:"humidity"`
+	} `json:"main"`
+	Visibility float64 `json:"visibility"`
+	Wind       struct {
+		Speed float64 `json:"speed"`
+		Deg   float64 `json:"deg"`
+	} `json:"wind"`
+	Clouds struct {
+		All float64 `json:"all"`
+	} `json:"clouds"`
+	Rain struct {
+		OneHour float64 `json:"1h"`
+	} `json:"rain"`
+	Dt int64 `json:"dt"`
+}
+
+func (o openWeatherMap) toObservation() domain.Observation {
+	var desc string
+	if len(o.Weather) > 0 {
+		desc = o.Weather[0].Description
+	}
+
+	var observedAt time.Time
+	if o.Dt != 0 {
+		observedAt = time.Unix(o.Dt, 0).UTC()
+	}
+
+	return domain.Observation{
+		TempC:       o.Main.Temp,
+		FeelsLikeC:  o.Main.FeelsLike,
+		Humidity:    o.Main.Humidity,
+		PressureHPa: o.Main.Pressure,
+		// units=metric reports the wind speed in m/s
+		WindSpeedKmph: o.Wind.Speed * 3.6,
+		WindDirDegree: o.Wind.Deg,
+		VisibilityKm:  o.Visibility / 1000,
+		CloudCover:    o.Clouds.All,
+		PrecipMM:      o.Rain.OneHour,
+		Description:   desc,
+		ObservedAt:    observedAt,
+		Source:        "openweathermap",
+	}
+}
@@ -0,0 +1,157 @@
+package openweathermap_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/openweathermap"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type OpenWeatherMapSuite struct {
+	suite.Suite
+}
+
+func TestOpenWeatherMapSuite(t *testing.T) {
+	suite.Run(t, new(OpenWeatherMapSuite))
+}
+
+func (s *OpenWeatherMapSuite) newGetter(rt roundTripperFunc) *http.Client {
+	return &http.Client{Transport: rt}
+}
+
+func (s *OpenWeatherMapSuite) TestFailedRequest() {
+	expectedErr := errors.New("request failed")
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, expectedErr
+	})
+
+	tg := openweathermap.NewTemperatureGetter(s.newGetter(rt), "key")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.ErrorIs(err, expectedErr)
+	s.Zero(temp)
+}
+
+func (s *OpenWeatherMapSuite) TestAPIError() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Body:       io.NopCloser(strings.NewReader(`{"cod":401,"message":"Invalid API key."}`)),
+		}, nil
+	})
+
+	tg := openweathermap.NewTemperatureGetter(s.newGetter(rt), "key")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	var apiErr openweathermap.ErrAPI
+	s.ErrorAs(err, &apiErr)
+	s.Equal(http.StatusUnauthorized, apiErr.Status)
+	s.Equal("Invalid API key.", apiErr.Message)
+	s.Zero(temp)
+}
+
+func (s *OpenWeatherMapSuite) TestLocationNotFound() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader(`{"cod":"404","message":"city not found"}`)),
+		}, nil
+	})
+
+	tg := openweathermap.NewTemperatureGetter(s.newGetter(rt), "key")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "Nowhere"})
+
+	s.ErrorIs(err, openweathermap.ErrLocationNotFound)
+	s.Zero(temp)
+}
+
+func (s *OpenWeatherMapSuite) TestInvalidJSON() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("{]")),
+		}, nil
+	})
+
+	tg := openweathermap.NewTemperatureGetter(s.newGetter(rt), "key")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.Error(err)
+	s.Zero(temp)
+}
+
+func (s *OpenWeatherMapSuite) TestSuccessfulObservation() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		s.Equal("secret", r.URL.Query().Get("appid"))
+		s.Equal("metric", r.URL.Query().Get("units"))
+
+		body := `{"weather":[{"description":"nuvens dispersas"}],` +
+			`"main":{"temp":25.0,"feels_like":27.0,"pressure":1015,"humidity":60},` +
+			`"visibility":10000,"wind":{"speed":5,"deg":120},"clouds":{"all":25},` +
+			`"rain":{"1h":0.1},"dt":1736949600}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	og := openweathermap.NewTemperatureGetter(s.newGetter(rt), "secret").(domain.ObservationGetter)
+	obs, err := og.GetObservation(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.NoError(err)
+	s.Equal(domain.Observation{
+		TempC:         25,
+		FeelsLikeC:    27,
+		Humidity:      60,
+		PressureHPa:   1015,
+		WindSpeedKmph: 18,
+		WindDirDegree: 120,
+		VisibilityKm:  10,
+		CloudCover:    25,
+		PrecipMM:      0.1,
+		Description:   "nuvens dispersas",
+		ObservedAt:    time.Date(2025, 1, 15, 14, 0, 0, 0, time.UTC),
+		Source:        "openweathermap",
+	}, obs)
+}
+
+func (s *OpenWeatherMapSuite) TestGeocodingMatchesUF() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/geo/1.0/direct" {
+			s.Equal("Bom Jesus,BR", r.URL.Query().Get("q"))
+			body := `[{"name":"Bom Jesus","lat":-28.67,"lon":-50.43,"country":"BR","state":"Rio Grande do Sul"},` +
+				`{"name":"Bom Jesus","lat":-9.07,"lon":-44.36,"country":"BR","state":"Piauí"}]`
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}
+
+		s.Empty(r.URL.Query().Get("q"))
+		s.Equal("-9.07", r.URL.Query().Get("lat"))
+		s.Equal("-44.36", r.URL.Query().Get("lon"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"main":{"temp":30.0}}`)),
+		}, nil
+	})
+
+	tg := openweathermap.NewTemperatureGetter(s.newGetter(rt), "key")
+
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "Bom Jesus", UF: "PI"})
+	s.NoError(err)
+	s.Equal(30.0, temp)
+
+	_, err = tg.GetTemperature(context.Background(), domain.Location{City: "Bom Jesus", UF: "SC"})
+	s.ErrorIs(err, openweathermap.ErrLocationNotFound)
+}
@@ -0,0 +1,108 @@
+// Package postmon implements [domain.AddressGetter] using Postmon.
+package postmon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+)
+
+const baseURL = "https://api.postmon.com.br/v1/cep/"
+
+// ErrStatusCode is returned when Postmon answers with an unexpected status.
+type ErrStatusCode struct {
+	Status int
+}
+
+// Error implements [error].
+func (e ErrStatusCode) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.Status)
+}
+
+// Postmon is a [domain.AddressGetter] backed by Postmon.
+type Postmon struct {
+	cl *http.Client
+}
+
+// NewAddressGetter returns a new implementation of [domain.AddressGetter].
+func NewAddressGetter(cl *http.Client) domain.AddressGetter {
+	return &Postmon{cl: cl}
+}
+
+// GetAddress implements [domain.AddressGetter].
+func (a *Postmon) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "get-address")
+	defer span.End()
+
+	u, err := url.JoinPath(baseURL, postalCode)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("mounting url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := a.cl.Do(req)
+	if err != nil {
+		return domain.Address{}, fmt.Errorf("doing request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return domain.Address{}, domain.ErrPostalCodeNotFound
+	default:
+		return domain.Address{}, ErrStatusCode{Status: res.StatusCode}
+	}
+
+	var body postmon
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return domain.Address{}, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return body.toAddress(postalCode), nil
+}
+
+type postmon struct {
+	Bairro     string `json:"bairro"`
+	Cidade     string `json:"cidade"`
+	Logradouro string `json:"logradouro"`
+	Cep        string `json:"cep"`
+	Estado     string `json:"estado"`
+	EstadoInfo struct {
+		AreaKm2    string `json:"area_km2"`
+		CodigoIbge string `json:"codigo_ibge"`
+		Nome       string `json:"nome"`
+	} `json:"estado_info"`
+	CidadeInfo struct {
+		AreaKm2    string `json:"area_km2"`
+		CodigoIbge string `json:"codigo_ibge"`
+	} `json:"cidade_info"`
+}
+
+func (p postmon) toAddress(postalCode string) domain.Address {
+	cep := domain.NormalizePostalCode(p.Cep)
+	if cep == "" {
+		cep = domain.NormalizePostalCode(postalCode)
+	}
+
+	return domain.Address{
+		PostalCode:   cep,
+		Street:       p.Logradouro,
+		Neighborhood: p.Bairro,
+		City:         p.Cidade,
+		UF:           p.Estado,
+		State:        p.EstadoInfo.Nome,
+		IBGE:         p.CidadeInfo.CodigoIbge,
+		Source:       "postmon",
+	}
+}
@@ -0,0 +1,120 @@
+package postmon_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/postmon"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type PostmonSuite struct {
+	suite.Suite
+}
+
+func TestPostmonSuite(t *testing.T) {
+	suite.Run(t, new(PostmonSuite))
+}
+
+func (s *PostmonSuite) newGetter(rt roundTripperFunc) *http.Client {
+	return &http.Client{Transport: rt}
+}
+
+func (s *PostmonSuite) TestFailedRequest() {
+	expectedErr := errors.New("request failed")
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, expectedErr
+	})
+
+	ag := postmon.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorIs(err, expectedErr)
+	s.Empty(addr)
+}
+
+func (s *PostmonSuite) TestNonOKStatusCode() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+
+	ag := postmon.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.ErrorAs(err, &postmon.ErrStatusCode{})
+	s.Empty(addr)
+}
+
+func (s *PostmonSuite) TestPostalCodeNotFound() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+
+	ag := postmon.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "99999999")
+
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Empty(addr)
+}
+
+func (s *PostmonSuite) TestInvalidJSON() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("{]")),
+		}, nil
+	})
+
+	ag := postmon.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.Error(err)
+	s.Empty(addr)
+}
+
+func (s *PostmonSuite) TestSuccessfulAddress() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		s.Equal("https://api.postmon.com.br/v1/cep/01001000", r.URL.String())
+
+		body := `{"bairro":"Sé","cidade":"São Paulo","logradouro":"Praça da Sé",` +
+			`"estado_info":{"area_km2":"248.219,481","codigo_ibge":"35","nome":"São Paulo"},` +
+			`"cep":"01001000","cidade_info":{"area_km2":"1521,11","codigo_ibge":"3550308"},` +
+			`"estado":"SP"}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	ag := postmon.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "01001000")
+
+	s.NoError(err)
+	s.Equal(domain.Address{
+		PostalCode:   "01001000",
+		Street:       "Praça da Sé",
+		Neighborhood: "Sé",
+		City:         "São Paulo",
+		UF:           "SP",
+		State:        "São Paulo",
+		IBGE:         "3550308",
+		Source:       "postmon",
+	}, addr)
+}
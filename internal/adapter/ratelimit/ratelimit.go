@@ -0,0 +1,241 @@
+// Package ratelimit implements an [http.RoundTripper] that keeps the requests
+// made to a provider under its rate limit and daily quota, so that it never
+// bans us for exceeding them.
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+)
+
+// Mode is what happens to a request over the rate limit.
+type Mode int
+
+const (
+	// Wait queues the request until the limit allows it, for at most
+	// [Limits.MaxWait] and never past the request deadline.
+	Wait Mode = iota
+	// Reject fails the request at once.
+	Reject
+)
+
+// Limits configures the limits of a provider.
+type Limits struct {
+	// Rate is how many requests per second are allowed on average. Zero
+	// means no rate limit.
+	Rate float64
+	// Burst is how many requests may be made at once. It defaults to 1.
+	Burst int
+	// Mode is what happens to requests over the rate limit.
+	Mode Mode
+	// MaxWait bounds how long a request is queued in [Wait] mode.
+	MaxWait time.Duration
+	// DailyQuota is how many requests may be made per UTC day. Zero means
+	// no quota.
+	DailyQuota int
+}
+
+// Limiter holds the state of the [Limits] of a provider: its rate and what
+// is left of its daily quota. A Limiter outlives the transports sharing it.
+type Limiter struct {
+	name     string
+	limits   Limits
+	bucket   *rate.Limiter
+	rejected metric.Int64Counter
+
+	mu   sync.Mutex
+	day  time.Time
+	used int
+}
+
+// NewLimiter returns a [Limiter] enforcing limits on the provider name.
+func NewLimiter(name string, limits Limits) *Limiter {
+	if limits.Burst <= 0 {
+		limits.Burst = 1
+	}
+
+	limit := rate.Inf
+	if limits.Rate > 0 {
+		limit = rate.Limit(limits.Rate)
+	}
+
+	rejected, _ := otel.Meter("service-b").Int64Counter(
+		"ratelimit.rejected",
+		metric.WithDescription("Requests not sent to a provider because of its limits"),
+	)
+
+	return &Limiter{
+		name:     name,
+		limits:   limits,
+		bucket:   rate.NewLimiter(limit, limits.Burst),
+		rejected: rejected,
+	}
+}
+
+// Transport returns a [Transport] sending requests through next, or
+// [http.DefaultTransport] if next is nil, under the limits of l.
+func (l *Limiter) Transport(next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{next: next, limiter: l}
+}
+
+// Transport is an [http.RoundTripper] enforcing the [Limits] of a provider.
+type Transport struct {
+	next    http.RoundTripper
+	limiter *Limiter
+}
+
+// NewTransport returns a [Transport] sending the requests for the provider
+// name through next, or [http.DefaultTransport] if next is nil.
+func NewTransport(next http.RoundTripper, name string, limits Limits) *Transport {
+	return NewLimiter(name, limits).Transport(next)
+}
+
+// NewClient returns a copy of cl whose requests go through a [Transport].
+func NewClient(cl *http.Client, name string, limits Limits) *http.Client {
+	c := *cl
+	c.Transport = NewTransport(cl.Transport, name, limits)
+	return &c
+}
+
+// RoundTrip implements [http.RoundTripper]. Requests over the limits fail
+// with [domain.ErrRateLimited].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if err := t.limiter.takeQuota(); err != nil {
+		t.limiter.reject(ctx, "quota")
+		return nil, err
+	}
+
+	if err := t.limiter.wait(ctx); err != nil {
+		t.limiter.returnQuota()
+		if ctx.Err() == nil {
+			t.limiter.reject(ctx, "rate")
+		}
+		return nil, err
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// Used returns how many requests were made in the current UTC day.
+func (t *Transport) Used() int {
+	return t.limiter.Used()
+}
+
+// Used returns how many requests were made in the current UTC day.
+func (l *Limiter) Used() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rollDay()
+	return l.used
+}
+
+func (l *Limiter) takeQuota() error {
+	if l.limits.DailyQuota <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rollDay()
+	if l.used >= l.limits.DailyQuota {
+		return domain.ErrRateLimited{Name: l.name, RetryAfter: time.Until(l.day.AddDate(0, 0, 1))}
+	}
+
+	l.used++
+	return nil
+}
+
+// returnQuota gives back the quota taken by a request that was not sent.
+func (l *Limiter) returnQuota() {
+	if l.limits.DailyQuota <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.used > 0 {
+		l.used--
+	}
+}
+
+// rollDay resets the quota when a new UTC day starts.
+func (l *Limiter) rollDay() {
+	if today := time.Now().UTC().Truncate(24 * time.Hour); !today.Equal(l.day) {
+		l.day, l.used = today, 0
+	}
+}
+
+func (l *Limiter) wait(ctx context.Context) error {
+	if l.limits.Mode == Reject {
+		if !l.bucket.Allow() {
+			return domain.ErrRateLimited{Name: l.name, RetryAfter: l.retryAfter()}
+		}
+		return nil
+	}
+
+	r := l.bucket.Reserve()
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+
+	if delay > l.limits.MaxWait {
+		r.Cancel()
+		return domain.ErrRateLimited{Name: l.name, RetryAfter: delay}
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		r.Cancel()
+		return domain.ErrRateLimited{Name: l.name, RetryAfter: delay}
+	}
+
+	trace.SpanFromContext(ctx).AddEvent("ratelimit-wait", trace.WithAttributes(
+		attribute.String("ratelimit.provider", l.name),
+		attribute.Int64("ratelimit.wait_ms", delay.Milliseconds()),
+	))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// retryAfter returns how long until the limiter has a token again.
+func (l *Limiter) retryAfter() time.Duration {
+	r := l.bucket.Reserve()
+	defer r.Cancel()
+
+	return r.Delay()
+}
+
+func (l *Limiter) reject(ctx context.Context, reason string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("ratelimit.provider", l.name),
+		attribute.String("ratelimit.reason", reason),
+	}
+	trace.SpanFromContext(ctx).AddEvent("ratelimit-rejected", trace.WithAttributes(attrs...))
+	l.rejected.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
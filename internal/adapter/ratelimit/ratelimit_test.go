@@ -0,0 +1,164 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/ratelimit"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type RateLimitSuite struct {
+	suite.Suite
+	calls atomic.Int32
+	next  roundTripperFunc
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitSuite))
+}
+
+func (s *RateLimitSuite) SetupTest() {
+	s.calls.Store(0)
+	s.next = func(r *http.Request) (*http.Response, error) {
+		s.calls.Add(1)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	}
+}
+
+func (s *RateLimitSuite) get(ctx context.Context, rt http.RoundTripper) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	s.Require().NoError(err)
+
+	res, err := rt.RoundTrip(req)
+	if err == nil {
+		res.Body.Close()
+	}
+	return err
+}
+
+func (s *RateLimitSuite) TestUnlimited() {
+	rt := ratelimit.NewTransport(s.next, "wttr", ratelimit.Limits{})
+
+	for range 100 {
+		s.NoError(s.get(context.Background(), rt))
+	}
+
+	s.Equal(int32(100), s.calls.Load())
+}
+
+func (s *RateLimitSuite) TestRejectMode() {
+	rt := ratelimit.NewTransport(s.next, "wttr", ratelimit.Limits{Rate: 1, Burst: 2, Mode: ratelimit.Reject})
+
+	s.NoError(s.get(context.Background(), rt))
+	s.NoError(s.get(context.Background(), rt))
+	err := s.get(context.Background(), rt)
+
+	var limited domain.ErrRateLimited
+	s.Require().ErrorAs(err, &limited)
+	s.Equal("wttr", limited.Name)
+	s.Positive(limited.RetryAfter)
+	s.LessOrEqual(limited.RetryAfter, time.Second)
+	s.Equal(int32(2), s.calls.Load())
+}
+
+func (s *RateLimitSuite) TestWaitMode() {
+	rt := ratelimit.NewTransport(s.next, "wttr", ratelimit.Limits{Rate: 20, Burst: 1, MaxWait: time.Second})
+
+	start := time.Now()
+	for range 3 {
+		s.NoError(s.get(context.Background(), rt))
+	}
+
+	// the second and third requests wait 50ms each
+	s.GreaterOrEqual(time.Since(start), 90*time.Millisecond)
+	s.Equal(int32(3), s.calls.Load())
+}
+
+func (s *RateLimitSuite) TestWaitModeBounded() {
+	rt := ratelimit.NewTransport(s.next, "wttr", ratelimit.Limits{Rate: 1, Burst: 1, MaxWait: 10 * time.Millisecond})
+
+	s.NoError(s.get(context.Background(), rt))
+	err := s.get(context.Background(), rt)
+
+	var limited domain.ErrRateLimited
+	s.ErrorAs(err, &limited)
+	s.Equal(int32(1), s.calls.Load())
+}
+
+func (s *RateLimitSuite) TestWaitModeRespectsDeadline() {
+	rt := ratelimit.NewTransport(s.next, "wttr", ratelimit.Limits{Rate: 1, Burst: 1, MaxWait: time.Minute})
+	s.NoError(s.get(context.Background(), rt))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := s.get(ctx, rt)
+
+	var limited domain.ErrRateLimited
+	s.ErrorAs(err, &limited)
+	s.Less(time.Since(start), 10*time.Millisecond)
+}
+
+func (s *RateLimitSuite) TestDailyQuota() {
+	rt := ratelimit.NewTransport(s.next, "weatherapi", ratelimit.Limits{DailyQuota: 2})
+
+	s.NoError(s.get(context.Background(), rt))
+	s.NoError(s.get(context.Background(), rt))
+	err := s.get(context.Background(), rt)
+
+	var limited domain.ErrRateLimited
+	s.Require().ErrorAs(err, &limited)
+	s.LessOrEqual(limited.RetryAfter, 24*time.Hour)
+	s.Equal(2, rt.Used())
+	s.Equal(int32(2), s.calls.Load())
+}
+
+func (s *RateLimitSuite) TestRejectedRequestsKeepQuota() {
+	rt := ratelimit.NewTransport(s.next, "weatherapi", ratelimit.Limits{
+		Rate:       1,
+		Burst:      1,
+		Mode:       ratelimit.Reject,
+		DailyQuota: 10,
+	})
+
+	s.NoError(s.get(context.Background(), rt))
+	s.Error(s.get(context.Background(), rt))
+
+	s.Equal(1, rt.Used())
+}
+
+func (s *RateLimitSuite) TestTransportsShareLimiter() {
+	limiter := ratelimit.NewLimiter("weatherapi", ratelimit.Limits{DailyQuota: 2})
+
+	s.NoError(s.get(context.Background(), limiter.Transport(s.next)))
+	s.NoError(s.get(context.Background(), limiter.Transport(s.next)))
+
+	var limited domain.ErrRateLimited
+	s.ErrorAs(s.get(context.Background(), limiter.Transport(s.next)), &limited)
+	s.Equal(2, limiter.Used())
+}
+
+func (s *RateLimitSuite) TestNewClient() {
+	cl := ratelimit.NewClient(&http.Client{Transport: s.next}, "wttr", ratelimit.Limits{DailyQuota: 1})
+
+	res, err := cl.Get("http://example.com")
+	s.Require().NoError(err)
+	res.Body.Close()
+
+	_, err = cl.Get("http://example.com")
+
+	var limited domain.ErrRateLimited
+	s.ErrorAs(err, &limited)
+}
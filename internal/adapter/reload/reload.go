@@ -0,0 +1,122 @@
+// Package reload forwards to a [domain.AddressGetter], a
+// [domain.TemperatureGetter] and a [domain.ForecastGetter] that can be
+// replaced, together and atomically, while serving. Calls in flight finish
+// on the getters they started on, and the calls of a request pinned with
+// [Chains.Pin] all go to the same getters even when they are replaced
+// meanwhile.
+package reload
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+)
+
+// Getters are the getters replaced together.
+type Getters struct {
+	AddressGetter     domain.AddressGetter
+	TemperatureGetter domain.TemperatureGetter
+	ForecastGetter    domain.ForecastGetter
+}
+
+type getters struct {
+	Getters
+	og domain.ObservationGetter
+}
+
+// Chains forwards to replaceable [Getters].
+type Chains struct {
+	current atomic.Pointer[getters]
+}
+
+// pinKey is the context key of the getters pinned by a [Chains].
+type pinKey struct{ c *Chains }
+
+// NewChains returns [Chains] forwarding to next.
+func NewChains(next Getters) *Chains {
+	c := &Chains{}
+	c.Store(next)
+	return c
+}
+
+// Store makes the following calls, and requests, go to next.
+func (c *Chains) Store(next Getters) {
+	c.current.Store(&getters{Getters: next, og: domain.AsObservationGetter(next.TemperatureGetter)})
+}
+
+// Load returns the current getters.
+func (c *Chains) Load() Getters {
+	return c.current.Load().Getters
+}
+
+// Pin returns a copy of ctx carrying the current getters, which the calls
+// made with it use instead of the ones stored later.
+func (c *Chains) Pin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pinKey{c}, c.current.Load())
+}
+
+// Middleware pins the current getters to the context of each request.
+func (c *Chains) Middleware(ctx *gin.Context) {
+	ctx.Request = ctx.Request.WithContext(c.Pin(ctx.Request.Context()))
+	ctx.Next()
+}
+
+func (c *Chains) load(ctx context.Context) *getters {
+	if g, ok := ctx.Value(pinKey{c}).(*getters); ok {
+		return g
+	}
+
+	return c.current.Load()
+}
+
+// AddressGetter returns a [domain.AddressGetter] forwarding to the current
+// address getter.
+func (c *Chains) AddressGetter() domain.AddressGetter {
+	return addressGetter{c}
+}
+
+// TemperatureGetter returns a [domain.TemperatureGetter], which also
+// implements [domain.ObservationGetter], forwarding to the current
+// temperature getter.
+func (c *Chains) TemperatureGetter() domain.TemperatureGetter {
+	return temperatureGetter{c}
+}
+
+// ForecastGetter returns a [domain.ForecastGetter] forwarding to the current
+// forecast getter.
+func (c *Chains) ForecastGetter() domain.ForecastGetter {
+	return forecastGetter{c}
+}
+
+type addressGetter struct{ c *Chains }
+
+// GetAddress implements [domain.AddressGetter].
+func (g addressGetter) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
+	return g.c.load(ctx).AddressGetter.GetAddress(ctx, postalCode)
+}
+
+type temperatureGetter struct{ c *Chains }
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (g temperatureGetter) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := g.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter].
+func (g temperatureGetter) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	return g.c.load(ctx).og.GetObservation(ctx, location)
+}
+
+type forecastGetter struct{ c *Chains }
+
+// GetForecast implements [domain.ForecastGetter].
+func (g forecastGetter) GetForecast(ctx context.Context, location domain.Location) (domain.Forecast, error) {
+	return g.c.load(ctx).ForecastGetter.GetForecast(ctx, location)
+}
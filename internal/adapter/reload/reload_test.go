@@ -0,0 +1,153 @@
+package reload_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/reload"
+)
+
+type mockAddressGetter struct {
+	city string
+}
+
+func (m mockAddressGetter) GetAddress(_ context.Context, postalCode string) (domain.Address, error) {
+	return domain.Address{PostalCode: postalCode, City: m.city}, nil
+}
+
+type mockTemperatureGetter struct {
+	temp float64
+}
+
+func (m mockTemperatureGetter) GetTemperature(_ context.Context, _ domain.Location) (float64, error) {
+	return m.temp, nil
+}
+
+type mockForecastGetter struct {
+	source string
+}
+
+func (m mockForecastGetter) GetForecast(_ context.Context, _ domain.Location) (domain.Forecast, error) {
+	return domain.Forecast{Source: m.source}, nil
+}
+
+type ReloadSuite struct {
+	suite.Suite
+}
+
+func TestReloadSuite(t *testing.T) {
+	suite.Run(t, new(ReloadSuite))
+}
+
+func getters(city string, temp float64, source string) reload.Getters {
+	return reload.Getters{
+		AddressGetter:     mockAddressGetter{city: city},
+		TemperatureGetter: mockTemperatureGetter{temp: temp},
+		ForecastGetter:    mockForecastGetter{source: source},
+	}
+}
+
+func (s *ReloadSuite) TestStore() {
+	c := reload.NewChains(getters("São Paulo", 25, "wttr"))
+
+	addr, err := c.AddressGetter().GetAddress(context.Background(), "01001000")
+	s.Require().NoError(err)
+	s.Equal("São Paulo", addr.City)
+
+	c.Store(getters("Campinas", 30, "openmeteo"))
+
+	addr, err = c.AddressGetter().GetAddress(context.Background(), "01001000")
+	s.Require().NoError(err)
+	s.Equal("Campinas", addr.City)
+
+	temp, err := c.TemperatureGetter().GetTemperature(context.Background(), domain.Location{})
+	s.Require().NoError(err)
+	s.InDelta(30.0, temp, 0.001)
+
+	obs, err := domain.AsObservationGetter(c.TemperatureGetter()).GetObservation(context.Background(), domain.Location{})
+	s.Require().NoError(err)
+	s.InDelta(30.0, obs.TempC, 0.001)
+
+	forecast, err := c.ForecastGetter().GetForecast(context.Background(), domain.Location{})
+	s.Require().NoError(err)
+	s.Equal("openmeteo", forecast.Source)
+}
+
+func (s *ReloadSuite) TestPinnedContextKeepsGetters() {
+	c := reload.NewChains(getters("São Paulo", 25, "wttr"))
+	ctx := c.Pin(context.Background())
+
+	c.Store(getters("Campinas", 30, "openmeteo"))
+
+	addr, err := c.AddressGetter().GetAddress(ctx, "01001000")
+	s.Require().NoError(err)
+	s.Equal("São Paulo", addr.City)
+
+	temp, err := c.TemperatureGetter().GetTemperature(ctx, domain.Location{})
+	s.Require().NoError(err)
+	s.InDelta(25.0, temp, 0.001)
+
+	forecast, err := c.ForecastGetter().GetForecast(ctx, domain.Location{})
+	s.Require().NoError(err)
+	s.Equal("wttr", forecast.Source)
+}
+
+func (s *ReloadSuite) TestPinIsPerChains() {
+	a := reload.NewChains(getters("São Paulo", 25, "wttr"))
+	b := reload.NewChains(getters("Campinas", 30, "openmeteo"))
+
+	addr, err := b.AddressGetter().GetAddress(a.Pin(context.Background()), "01001000")
+	s.Require().NoError(err)
+	s.Equal("Campinas", addr.City)
+}
+
+func (s *ReloadSuite) TestMiddlewarePinsEachRequest() {
+	gin.SetMode(gin.TestMode)
+	c := reload.NewChains(getters("São Paulo", 25, "wttr"))
+
+	engine := gin.New()
+	engine.Use(c.Middleware)
+	engine.GET("/", func(ctx *gin.Context) {
+		addr, _ := c.AddressGetter().GetAddress(ctx.Request.Context(), "01001000")
+		c.Store(getters("Campinas", 30, "openmeteo"))
+		temp, _ := c.TemperatureGetter().GetTemperature(ctx.Request.Context(), domain.Location{})
+		ctx.String(http.StatusOK, "%s %.0f", addr.City, temp)
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	s.Equal("São Paulo 25", w.Body.String())
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	s.Equal("Campinas 30", w.Body.String())
+}
+
+func (s *ReloadSuite) TestConcurrentStore() {
+	c := reload.NewChains(getters("São Paulo", 25, "wttr"))
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			for range 100 {
+				ctx := c.Pin(context.Background())
+				addr, err := c.AddressGetter().GetAddress(ctx, "01001000")
+				s.NoError(err)
+				forecast, err := c.ForecastGetter().GetForecast(ctx, domain.Location{})
+				s.NoError(err)
+				s.Contains([][2]string{{"São Paulo", "wttr"}, {"Campinas", "openmeteo"}},
+					[2]string{addr.City, forecast.Source})
+			}
+		})
+	}
+	for range 100 {
+		c.Store(getters("Campinas", 30, "openmeteo"))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,214 @@
+// Package retry implements an [http.RoundTripper] that retries transient
+// upstream failures with capped exponential backoff and jitter.
+//
+// Only idempotent requests are retried, and only after a network error or a
+// 429 or 5xx response. Any other response, such as the 404 the CEP adapters
+// turn into domain.ErrPostalCodeNotFound, is returned at once. When every
+// attempt fails the last response is returned as is, so the adapters still
+// report it with their ErrStatusCode. Requests refused by a rate limiter
+// with [domain.ErrRateLimited], or by a full bulkhead with
+// [domain.ErrBulkheadFull], are not retried either.
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Policy configures how requests are retried.
+type Policy struct {
+	// MaxAttempts is how many times a request is sent at most, counting the
+	// first one.
+	MaxAttempts int
+	// BaseDelay is the backoff before the second attempt, doubled before
+	// every following one.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff, but not the delay asked by a Retry-After
+	// header.
+	MaxDelay time.Duration
+}
+
+// DefaultPolicy is the [Policy] used for the zero fields of the one given to
+// [NewTransport].
+var DefaultPolicy = Policy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// Transport is an [http.RoundTripper] retrying the requests sent through
+// another one.
+type Transport struct {
+	next   http.RoundTripper
+	policy Policy
+}
+
+// NewTransport returns a [Transport] sending requests through next, or
+// [http.DefaultTransport] if next is nil.
+func NewTransport(next http.RoundTripper, policy Policy) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultPolicy.MaxAttempts
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = DefaultPolicy.BaseDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = DefaultPolicy.MaxDelay
+	}
+
+	return &Transport{next: next, policy: policy}
+}
+
+// RoundTrip implements [http.RoundTripper]. Every attempt runs in its own
+// span, child of the request's one. Waits that would outlive the request
+// deadline are not started: the last outcome is returned instead.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !retryable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		res, err := t.attempt(ctx, req, attempt)
+
+		if attempt == t.policy.MaxAttempts || !transient(ctx, res, err) {
+			return res, err
+		}
+
+		delay := t.backoff(attempt)
+		if res != nil {
+			if ra, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+				delay = ra
+			}
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return res, err
+		}
+
+		if res != nil {
+			// the connection can only be reused once the body is consumed
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 4<<10))
+			res.Body.Close()
+		}
+
+		trace.SpanFromContext(ctx).AddEvent("retry-backoff", trace.WithAttributes(
+			attribute.Int("http.attempt", attempt+1),
+			attribute.Int64("retry.delay_ms", delay.Milliseconds()),
+		))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *Transport) attempt(ctx context.Context, req *http.Request, attempt int) (*http.Response, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "http-attempt", trace.WithAttributes(
+		attribute.Int("http.attempt", attempt),
+	))
+	defer span.End()
+
+	r := req.Clone(ctx)
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	res, err := t.next.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	if res.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, res.Status)
+	}
+
+	return res, nil
+}
+
+// backoff returns a random delay between zero and the capped exponential
+// backoff of attempt ("full jitter"), so that clients failing together do
+// not retry together.
+func (t *Transport) backoff(attempt int) time.Duration {
+	d := t.policy.MaxDelay
+	if shift := attempt - 1; shift < 32 {
+		d = min(d, t.policy.BaseDelay<<shift)
+	}
+	return rand.N(d) + 1
+}
+
+// retryable reports whether req may be sent again: its method must be
+// idempotent and its body, if any, replayable.
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// transient reports whether an attempt failed in a way worth retrying.
+func transient(ctx context.Context, res *http.Response, err error) bool {
+	if err != nil {
+		var (
+			limited   domain.ErrRateLimited
+			full      domain.ErrBulkheadFull
+			untrusted domain.ErrUntrustedCertificate
+		)
+		if errors.As(err, &limited) || errors.As(err, &full) || errors.As(err, &untrusted) {
+			return false
+		}
+		// the caller giving up is not an upstream failure
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+
+	return res.StatusCode == http.StatusTooManyRequests ||
+		(res.StatusCode >= http.StatusInternalServerError && res.StatusCode != http.StatusNotImplemented)
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an
+// HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, time.Until(t)), true
+	}
+
+	return 0, false
+}
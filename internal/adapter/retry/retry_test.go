@@ -0,0 +1,255 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/retry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// sequence answers with the given statuses in order, then with 200; a zero
+// status is a network error.
+func sequence(calls *atomic.Int32, statuses ...int) roundTripperFunc {
+	return func(r *http.Request) (*http.Response, error) {
+		i := int(calls.Add(1)) - 1
+		status := http.StatusOK
+		if i < len(statuses) {
+			status = statuses[i]
+		}
+		if status == 0 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("{}")),
+			Request:    r,
+		}, nil
+	}
+}
+
+var policy = retry.Policy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+}
+
+type RetrySuite struct {
+	suite.Suite
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(RetrySuite))
+}
+
+func (s *RetrySuite) get(ctx context.Context, rt http.RoundTripper) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	s.Require().NoError(err)
+	return rt.RoundTrip(req)
+}
+
+func (s *RetrySuite) TestRetriesTransientStatus() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(sequence(&calls, 502, 503), policy)
+
+	res, err := s.get(context.Background(), rt)
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.Equal(int32(3), calls.Load())
+}
+
+func (s *RetrySuite) TestRetriesNetworkError() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(sequence(&calls, 0), policy)
+
+	res, err := s.get(context.Background(), rt)
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.Equal(int32(2), calls.Load())
+}
+
+func (s *RetrySuite) TestRetriesTooManyRequests() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(sequence(&calls, 429), policy)
+
+	res, err := s.get(context.Background(), rt)
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.Equal(int32(2), calls.Load())
+}
+
+func (s *RetrySuite) TestReturnsLastFailure() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(sequence(&calls, 500, 502, 503, 504), policy)
+
+	res, err := s.get(context.Background(), rt)
+
+	s.NoError(err)
+	s.Equal(http.StatusServiceUnavailable, res.StatusCode)
+	s.Equal(int32(3), calls.Load())
+}
+
+func (s *RetrySuite) TestDoesNotRetryClientErrors() {
+	for _, status := range []int{400, 404, 501} {
+		var calls atomic.Int32
+		rt := retry.NewTransport(sequence(&calls, status), policy)
+
+		res, err := s.get(context.Background(), rt)
+
+		s.NoError(err)
+		s.Equal(status, res.StatusCode)
+		s.Equal(int32(1), calls.Load(), status)
+	}
+}
+
+func (s *RetrySuite) TestDoesNotRetryPost() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(sequence(&calls, 503), policy)
+
+	req, err := http.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("{}"))
+	s.Require().NoError(err)
+	res, err := rt.RoundTrip(req)
+
+	s.NoError(err)
+	s.Equal(http.StatusServiceUnavailable, res.StatusCode)
+	s.Equal(int32(1), calls.Load())
+}
+
+func (s *RetrySuite) TestHonoursRetryAfter() {
+	var calls atomic.Int32
+	var first time.Time
+	rt := retry.NewTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: r}
+		if calls.Add(1) == 1 {
+			first = time.Now()
+			res.StatusCode = http.StatusServiceUnavailable
+			res.Header.Set("Retry-After", "1")
+		}
+		return res, nil
+	}), policy)
+
+	res, err := s.get(context.Background(), rt)
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, res.StatusCode)
+	s.GreaterOrEqual(time.Since(first), time.Second)
+}
+
+func (s *RetrySuite) TestRespectsDeadline() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		res := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}, Body: http.NoBody, Request: r}
+		res.Header.Set("Retry-After", "60")
+		return res, nil
+	}), policy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	res, err := s.get(ctx, rt)
+
+	s.NoError(err)
+	s.Equal(http.StatusServiceUnavailable, res.StatusCode)
+	s.Equal(int32(1), calls.Load())
+	s.Less(time.Since(start), time.Second)
+}
+
+func (s *RetrySuite) TestDoesNotRetryCancellation() {
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	rt := retry.NewTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		cancel()
+		return nil, r.Context().Err()
+	}), policy)
+
+	_, err := s.get(ctx, rt)
+
+	s.ErrorIs(err, context.Canceled)
+	s.Equal(int32(1), calls.Load())
+}
+
+func (s *RetrySuite) TestDoesNotRetryRateLimited() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return nil, domain.ErrRateLimited{Name: "wttr", RetryAfter: time.Second}
+	}), policy)
+
+	_, err := s.get(context.Background(), rt)
+
+	var limited domain.ErrRateLimited
+	s.ErrorAs(err, &limited)
+	s.Equal(int32(1), calls.Load())
+}
+
+func (s *RetrySuite) TestDoesNotRetryBulkheadFull() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return nil, domain.ErrBulkheadFull{Name: "wttr"}
+	}), policy)
+
+	_, err := s.get(context.Background(), rt)
+
+	var full domain.ErrBulkheadFull
+	s.ErrorAs(err, &full)
+	s.Equal(int32(1), calls.Load())
+}
+
+func (s *RetrySuite) TestDoesNotRetryUntrustedCertificate() {
+	var calls atomic.Int32
+	rt := retry.NewTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return nil, domain.ErrUntrustedCertificate{Name: "wttr", Err: errors.New("unknown authority")}
+	}), policy)
+
+	_, err := s.get(context.Background(), rt)
+
+	var untrusted domain.ErrUntrustedCertificate
+	s.ErrorAs(err, &untrusted)
+	s.Equal(int32(1), calls.Load())
+}
+
+func (s *RetrySuite) TestAttemptSpans() {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(trace.NewTracerProvider(trace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	var calls atomic.Int32
+	rt := retry.NewTransport(sequence(&calls, 502), policy)
+
+	_, err := s.get(context.Background(), rt)
+	s.NoError(err)
+
+	spans := recorder.Ended()
+	s.Require().Len(spans, 2)
+	for i, span := range spans {
+		s.Equal("http-attempt", span.Name())
+		s.Contains(span.Attributes(), attribute.Int("http.attempt", i+1))
+	}
+}
@@ -3,12 +3,15 @@ package servicea
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/deadline"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 )
@@ -20,23 +23,40 @@ type Handler struct {
 	client      *http.Client
 }
 
-// NewHandler TODO
-func NewHandler(serviceBURL string) http.Handler {
+// NewHandler TODO. Service B is called through transport, or
+// [http.DefaultTransport] when nil. The middleware, such as a client rate
+// limiter, runs before every route.
+func NewHandler(serviceBURL string, transport http.RoundTripper, middleware ...gin.HandlerFunc) http.Handler {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
 	h := &Handler{
 		Engine:      gin.New(),
 		serviceBURL: serviceBURL,
-		client:      &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)},
+		client:      &http.Client{Transport: otelhttp.NewTransport(transport)},
 	}
 
 	h.Use(h.errorMiddleware)
+	h.Use(middleware...)
 
 	h.POST("/temperature", h.GetTemperature)
+	h.POST("/forecast", h.GetForecast)
 
 	return h
 }
 
 // GetTemperature TODO
 func (h *Handler) GetTemperature(ctx *gin.Context) {
+	h.forward(ctx, "temperature")
+}
+
+// GetForecast forwards a forecast request to service B.
+func (h *Handler) GetForecast(ctx *gin.Context) {
+	h.forward(ctx, "forecast")
+}
+
+func (h *Handler) forward(ctx *gin.Context, path string) {
 	reqCtx, span := otel.Tracer("service-a").Start(ctx.Request.Context(), "forward-to-service-b")
 	defer span.End()
 
@@ -54,12 +74,20 @@ func (h *Handler) GetTemperature(ctx *gin.Context) {
 		return
 	}
 
-	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, h.serviceBURL, w)
+	u, err := url.JoinPath(h.serviceBURL, path)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, u, w)
 	if err != nil {
 		ctx.Error(err)
 		return
 	}
 
+	deadline.Inject(reqCtx, req.Header)
+
 	res, err := h.client.Do(req)
 	if err != nil {
 		ctx.Error(err)
@@ -68,6 +96,11 @@ func (h *Handler) GetTemperature(ctx *gin.Context) {
 
 	defer res.Body.Close()
 
+	// lets clients back off when a provider of service B is unavailable
+	if ra := res.Header.Get("Retry-After"); ra != "" {
+		ctx.Header("Retry-After", ra)
+	}
+
 	// preferia usar io.Copy, mas é melhor deixar o chi fazer marshal pra o
 	// Conten-Type ser setado corretamente
 	var response map[string]any
@@ -97,7 +130,7 @@ func (h *Handler) errorMiddleware(ctx *gin.Context) {
 
 	ctx.Next()
 
-	if ctx.Errors == nil {
+	if len(ctx.Errors) == 0 {
 		return
 	}
 
@@ -109,6 +142,9 @@ func (h *Handler) errorMiddleware(ctx *gin.Context) {
 		statusCode = http.StatusNotFound
 	case errors.Is(err, domain.ErrInvalidZipCode):
 		statusCode = http.StatusUnprocessableEntity
+	case errors.Is(err, context.DeadlineExceeded):
+		ctx.JSON(http.StatusGatewayTimeout, Err{Error: errDeadlineExceeded})
+		return
 	default:
 		statusCode = http.StatusInternalServerError
 	}
@@ -116,6 +152,9 @@ func (h *Handler) errorMiddleware(ctx *gin.Context) {
 	ctx.JSON(statusCode, Err{Error: err.Error()})
 }
 
+// errDeadlineExceeded is the error of requests that ran out of their budget.
+const errDeadlineExceeded = "request deadline exceeded"
+
 // Response TODO
 type Response struct {
 	City  string  `json:"city"`
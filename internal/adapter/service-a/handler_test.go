@@ -0,0 +1,62 @@
+package servicea_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	servicea "github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/service-a"
+)
+
+type HandlerSuite struct {
+	suite.Suite
+	serviceB *httptest.Server
+	handler  http.Handler
+}
+
+func TestHandlerSuite(t *testing.T) {
+	suite.Run(t, new(HandlerSuite))
+}
+
+func (s *HandlerSuite) SetupTest() {
+	s.serviceB = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"city":"São Paulo","temp_C":25}`))
+	}))
+	s.handler = servicea.NewHandler(s.serviceB.URL, nil)
+}
+
+func (s *HandlerSuite) TearDownTest() {
+	s.serviceB.Close()
+}
+
+func (s *HandlerSuite) post(body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(body)))
+	return rec
+}
+
+func (s *HandlerSuite) TestForward() {
+	rec := s.post(`{"cep":"01001000"}`)
+	s.Require().Equal(http.StatusOK, rec.Code)
+
+	var resp servicea.Response
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(&resp))
+	s.Equal("São Paulo", resp.City)
+	s.Equal(25.0, resp.TempC)
+}
+
+func (s *HandlerSuite) TestInvalidZipCode() {
+	rec := s.post(`{"cep":"123"}`)
+	s.Equal(http.StatusUnprocessableEntity, rec.Code)
+}
+
+func (s *HandlerSuite) TestSuccessAfterError() {
+	s.Equal(http.StatusUnprocessableEntity, s.post(`{"cep":"123"}`).Code)
+
+	// the context, and its emptied errors, are reused by the next request
+	s.Equal(http.StatusOK, s.post(`{"cep":"01001000"}`).Code)
+}
@@ -0,0 +1,86 @@
+package serviceb
+
+import "github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+
+// ForecastResponse is the body returned by the forecast endpoints.
+type ForecastResponse struct {
+	City    string          `json:"city"`
+	Address *domain.Address `json:"address,omitempty"`
+	Days    []ForecastDay   `json:"days"`
+	Source  string          `json:"source,omitempty"`
+}
+
+// ForecastDay is a [domain.ForecastDay] with temperatures in Celsius,
+// Fahrenheit and Kelvin.
+type ForecastDay struct {
+	Date   string         `json:"date"`
+	MinC   float64        `json:"min_C"`
+	MinF   float64        `json:"min_F"`
+	MinK   float64        `json:"min_K"`
+	MaxC   float64        `json:"max_C"`
+	MaxF   float64        `json:"max_F"`
+	MaxK   float64        `json:"max_K"`
+	AvgC   float64        `json:"avg_C"`
+	AvgF   float64        `json:"avg_F"`
+	AvgK   float64        `json:"avg_K"`
+	Hourly []ForecastHour `json:"hourly"`
+}
+
+// ForecastHour is a [domain.ForecastHour] with temperatures in Celsius,
+// Fahrenheit and Kelvin.
+type ForecastHour struct {
+	Time         string  `json:"time"`
+	TempC        float64 `json:"temp_C"`
+	TempF        float64 `json:"temp_F"`
+	TempK        float64 `json:"temp_K"`
+	FeelsLikeC   float64 `json:"feels_like_C"`
+	FeelsLikeF   float64 `json:"feels_like_F"`
+	FeelsLikeK   float64 `json:"feels_like_K"`
+	Humidity     float64 `json:"humidity"`
+	ChanceOfRain float64 `json:"chance_of_rain"`
+	PrecipMM     float64 `json:"precip_mm"`
+	Description  string  `json:"description,omitempty"`
+}
+
+func newForecastResponse(address domain.Address, forecast domain.Forecast) ForecastResponse {
+	days := make([]ForecastDay, 0, len(forecast.Days))
+	for _, d := range forecast.Days {
+		hours := make([]ForecastHour, 0, len(d.Hourly))
+		for _, h := range d.Hourly {
+			hours = append(hours, ForecastHour{
+				Time:         h.Time,
+				TempC:        h.TempC,
+				TempF:        toFahrenheit(h.TempC),
+				TempK:        toKelvin(h.TempC),
+				FeelsLikeC:   h.FeelsLikeC,
+				FeelsLikeF:   toFahrenheit(h.FeelsLikeC),
+				FeelsLikeK:   toKelvin(h.FeelsLikeC),
+				Humidity:     h.Humidity,
+				ChanceOfRain: h.ChanceOfRain,
+				PrecipMM:     h.PrecipMM,
+				Description:  h.Description,
+			})
+		}
+
+		days = append(days, ForecastDay{
+			Date:   d.Date,
+			MinC:   d.MinC,
+			MinF:   toFahrenheit(d.MinC),
+			MinK:   toKelvin(d.MinC),
+			MaxC:   d.MaxC,
+			MaxF:   toFahrenheit(d.MaxC),
+			MaxK:   toKelvin(d.MaxC),
+			AvgC:   d.AvgC,
+			AvgF:   toFahrenheit(d.AvgC),
+			AvgK:   toKelvin(d.AvgC),
+			Hourly: hours,
+		})
+	}
+
+	return ForecastResponse{
+		City:    address.City,
+		Address: &address,
+		Days:    days,
+		Source:  forecast.Source,
+	}
+}
@@ -2,39 +2,93 @@
 package serviceb
 
 import (
+	"context"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Handler TODO
 type Handler struct {
 	*gin.Engine
 	ag domain.AddressGetter
-	tg domain.TemperatureGetter
+	gc domain.Geocoder
+	og domain.ObservationGetter
+	fg domain.ForecastGetter
 }
 
 // NewHandler TODO
-func NewHandler(ag domain.AddressGetter, tg domain.TemperatureGetter) http.Handler {
-	h := &Handler{Engine: gin.New(), ag: ag, tg: tg}
+//
+// gc may be nil, in which case the weather is always looked up by city name.
+// The middleware, such as a deadline, runs before every route.
+func NewHandler(
+	ag domain.AddressGetter,
+	gc domain.Geocoder,
+	tg domain.TemperatureGetter,
+	fg domain.ForecastGetter,
+	middleware ...gin.HandlerFunc,
+) http.Handler {
+	h := &Handler{Engine: gin.New(), ag: ag, gc: gc, og: domain.AsObservationGetter(tg), fg: fg}
 
 	h.Use(h.errorMiddleware)
+	h.Use(middleware...)
 
 	h.POST("/temperature", h.GetTemperature)
+	h.POST("/forecast", h.GetForecast)
+	h.GET("/forecast/:cep", h.GetForecast)
 
 	return h
 }
 
 // GetTemperature TODO
 func (h *Handler) GetTemperature(ctx *gin.Context) {
-	propagator := otel.GetTextMapPropagator()
-	reqCtx := propagator.Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+	reqCtx, span := h.startSpan(ctx, "handle-temperature")
+	defer span.End()
+
+	postalCode, err := h.getPostalCode(ctx)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	address, err := h.ag.GetAddress(reqCtx, postalCode)
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
 
-	reqCtx, span := otel.Tracer("service-b").Start(reqCtx, "handle-temperature")
+	obs, err := h.og.GetObservation(reqCtx, h.locate(reqCtx, address))
+	if err != nil {
+		_ = ctx.Error(err)
+		return
+	}
+
+	c := obs.TempC
+
+	ctx.JSON(http.StatusOK, Response{
+		City:       address.City,
+		TempC:      c,
+		TempF:      toFahrenheit(c),
+		TempK:      toKelvin(c),
+		Stale:      obs.Stale,
+		AgeSeconds: int64(obs.Age.Seconds()),
+		Address:    &address,
+		Current:    &obs,
+	})
+}
+
+// GetForecast responds with the daily and hourly forecast for the city of
+// the CEP, either sent in the body or as the :cep path parameter.
+func (h *Handler) GetForecast(ctx *gin.Context) {
+	reqCtx, span := h.startSpan(ctx, "handle-forecast")
 	defer span.End()
 
 	postalCode, err := h.getPostalCode(ctx)
@@ -43,25 +97,54 @@ func (h *Handler) GetTemperature(ctx *gin.Context) {
 		return
 	}
 
-	location, err := h.ag.GetAddress(reqCtx, postalCode)
+	address, err := h.ag.GetAddress(reqCtx, postalCode)
 	if err != nil {
 		_ = ctx.Error(err)
 		return
 	}
 
-	c, err := h.tg.GetTemperature(reqCtx, location)
+	forecast, err := h.fg.GetForecast(reqCtx, h.locate(reqCtx, address))
 	if err != nil {
 		_ = ctx.Error(err)
 		return
 	}
 
-	f := c*1.8 + 32
-	k := c + 273
+	ctx.JSON(http.StatusOK, newForecastResponse(address, forecast))
+}
+
+// locate returns the location used to look up the weather of address. A
+// geocoding failure is only recorded, since the city name is still usable.
+func (h *Handler) locate(ctx context.Context, address domain.Address) domain.Location {
+	loc := domain.Location{City: address.City, UF: address.UF}
+	if h.gc == nil {
+		return loc
+	}
+
+	coords, err := h.gc.Geocode(ctx, address)
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		return loc
+	}
 
-	ctx.JSON(http.StatusOK, Response{City: location, TempC: c, TempF: f, TempK: k})
+	loc.Coordinates = &coords
+	return loc
+}
+
+func (h *Handler) startSpan(ctx *gin.Context, name string) (context.Context, trace.Span) {
+	propagator := otel.GetTextMapPropagator()
+	reqCtx := propagator.Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+
+	return otel.Tracer("service-b").Start(reqCtx, name)
 }
 
 func (h *Handler) getPostalCode(ctx *gin.Context) (string, error) {
+	if cep, ok := ctx.Params.Get("cep"); ok {
+		if len(cep) != 8 {
+			return "", domain.ErrInvalidZipCode
+		}
+		return cep, nil
+	}
+
 	var body map[string]any
 	if err := ctx.BindJSON(&body); err != nil {
 		return "", domain.ErrInvalidZipCode
@@ -79,18 +162,37 @@ func (h *Handler) errorMiddleware(ctx *gin.Context) {
 
 	ctx.Next()
 
-	if ctx.Errors == nil {
+	if len(ctx.Errors) == 0 {
 		return
 	}
 
 	err := ctx.Errors.Last()
 
-	var statusCode int
+	var (
+		statusCode   int
+		circuitOpen  domain.ErrCircuitOpen
+		rateLimited  domain.ErrRateLimited
+		bulkheadFull domain.ErrBulkheadFull
+		untrusted    domain.ErrUntrustedCertificate
+	)
 	switch {
 	case errors.Is(err, domain.ErrPostalCodeNotFound):
 		statusCode = http.StatusNotFound
 	case errors.Is(err, domain.ErrInvalidZipCode):
 		statusCode = http.StatusUnprocessableEntity
+	case errors.As(err, &circuitOpen):
+		statusCode = http.StatusServiceUnavailable
+		ctx.Header("Retry-After", retryAfter(circuitOpen.RetryAfter))
+	case errors.As(err, &rateLimited):
+		statusCode = http.StatusServiceUnavailable
+		ctx.Header("Retry-After", retryAfter(rateLimited.RetryAfter))
+	case errors.As(err, &bulkheadFull):
+		statusCode = http.StatusServiceUnavailable
+	case errors.As(err, &untrusted):
+		statusCode = http.StatusBadGateway
+	case errors.Is(err, context.DeadlineExceeded):
+		ctx.JSON(http.StatusGatewayTimeout, Err{Error: errDeadlineExceeded})
+		return
 	default:
 		statusCode = http.StatusInternalServerError
 	}
@@ -98,12 +200,37 @@ func (h *Handler) errorMiddleware(ctx *gin.Context) {
 	ctx.JSON(statusCode, Err{Error: err.Error()})
 }
 
+// errDeadlineExceeded is the error of requests that ran out of their budget.
+const errDeadlineExceeded = "request deadline exceeded"
+
+// retryAfter formats d as the whole seconds of a Retry-After header, rounded
+// up so that clients never retry too early.
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(max(1, int(math.Ceil(d.Seconds()))))
+}
+
+func toFahrenheit(c float64) float64 {
+	return c*1.8 + 32
+}
+
+func toKelvin(c float64) float64 {
+	return c + 273
+}
+
 // Response TODO
 type Response struct {
 	City  string  `json:"city"`
 	TempC float64 `json:"temp_C"`
 	TempF float64 `json:"temp_F"`
 	TempK float64 `json:"temp_K"`
+
+	// Stale is set when the temperature was served from cache after its
+	// freshness lifetime, AgeSeconds being how old it is.
+	Stale      bool  `json:"stale,omitempty"`
+	AgeSeconds int64 `json:"age_seconds,omitempty"`
+
+	Address *domain.Address     `json:"address,omitempty"`
+	Current *domain.Observation `json:"current,omitempty"`
 }
 
 // Err TODO
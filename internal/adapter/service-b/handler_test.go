@@ -4,21 +4,25 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/deadline"
 	serviceb "github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/service-b"
 )
 
 type mockAddressGetter struct {
-	address string
+	address domain.Address
 	err     error
 }
 
-func (m *mockAddressGetter) GetAddress(_ context.Context, _ string) (string, error) {
+func (m *mockAddressGetter) GetAddress(_ context.Context, _ string) (domain.Address, error) {
 	return m.address, m.err
 }
 
@@ -27,10 +31,51 @@ type mockTemperatureGetter struct {
 	err  error
 }
 
-func (m *mockTemperatureGetter) GetTemperature(_ context.Context, _ string) (float64, error) {
+func (m *mockTemperatureGetter) GetTemperature(_ context.Context, _ domain.Location) (float64, error) {
 	return m.temp, m.err
 }
 
+type mockObservationGetter struct {
+	obs      domain.Observation
+	err      error
+	location domain.Location
+}
+
+func (m *mockObservationGetter) GetTemperature(_ context.Context, _ domain.Location) (float64, error) {
+	return m.obs.TempC, m.err
+}
+
+func (m *mockObservationGetter) GetObservation(_ context.Context, location domain.Location) (domain.Observation, error) {
+	m.location = location
+	return m.obs, m.err
+}
+
+// blockingTemperatureGetter answers only when the context is done.
+type blockingTemperatureGetter struct{}
+
+func (blockingTemperatureGetter) GetTemperature(ctx context.Context, _ domain.Location) (float64, error) {
+	<-ctx.Done()
+	return 0, fmt.Errorf("doing request: %w", ctx.Err())
+}
+
+type mockGeocoder struct {
+	coords domain.Coordinates
+	err    error
+}
+
+func (m *mockGeocoder) Geocode(_ context.Context, _ domain.Address) (domain.Coordinates, error) {
+	return m.coords, m.err
+}
+
+type mockForecastGetter struct {
+	forecast domain.Forecast
+	err      error
+}
+
+func (m *mockForecastGetter) GetForecast(_ context.Context, _ domain.Location) (domain.Forecast, error) {
+	return m.forecast, m.err
+}
+
 type HandlerSuite struct {
 	suite.Suite
 }
@@ -40,7 +85,7 @@ func TestHandlerSuite(t *testing.T) {
 }
 
 func (s *HandlerSuite) TestInvalidPostalCodeTooShort() {
-	h := serviceb.NewHandler(&mockAddressGetter{}, &mockTemperatureGetter{})
+	h := serviceb.NewHandler(&mockAddressGetter{}, nil, &mockTemperatureGetter{}, &mockForecastGetter{})
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"123"}`))
 
@@ -50,7 +95,7 @@ func (s *HandlerSuite) TestInvalidPostalCodeTooShort() {
 }
 
 func (s *HandlerSuite) TestInvalidPostalCodeEmpty() {
-	h := serviceb.NewHandler(&mockAddressGetter{}, &mockTemperatureGetter{})
+	h := serviceb.NewHandler(&mockAddressGetter{}, nil, &mockTemperatureGetter{}, &mockForecastGetter{})
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":""}`))
 
@@ -61,7 +106,7 @@ func (s *HandlerSuite) TestInvalidPostalCodeEmpty() {
 
 func (s *HandlerSuite) TestAddressGetterError() {
 	ag := &mockAddressGetter{err: errors.New("not found")}
-	h := serviceb.NewHandler(ag, &mockTemperatureGetter{})
+	h := serviceb.NewHandler(ag, nil, &mockTemperatureGetter{}, &mockForecastGetter{})
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
 
@@ -71,9 +116,9 @@ func (s *HandlerSuite) TestAddressGetterError() {
 }
 
 func (s *HandlerSuite) TestTemperatureGetterError() {
-	ag := &mockAddressGetter{address: "São Paulo"}
+	ag := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
 	tg := &mockTemperatureGetter{err: errors.New("service unavailable")}
-	h := serviceb.NewHandler(ag, tg)
+	h := serviceb.NewHandler(ag, nil, tg, &mockForecastGetter{})
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
 
@@ -82,10 +127,74 @@ func (s *HandlerSuite) TestTemperatureGetterError() {
 	s.Equal(http.StatusInternalServerError, rec.Code)
 }
 
+func (s *HandlerSuite) TestCircuitOpen() {
+	ag := &mockAddressGetter{err: domain.ErrCircuitOpen{Name: "viacep", RetryAfter: 2500 * time.Millisecond}}
+	h := serviceb.NewHandler(ag, nil, &mockTemperatureGetter{}, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusServiceUnavailable, rec.Code)
+	s.Equal("3", rec.Header().Get("Retry-After"))
+}
+
+func (s *HandlerSuite) TestRateLimited() {
+	tg := &mockTemperatureGetter{err: fmt.Errorf("doing request: %w", domain.ErrRateLimited{Name: "wttr", RetryAfter: time.Hour})}
+	h := serviceb.NewHandler(&mockAddressGetter{address: domain.Address{City: "São Paulo"}}, nil, tg, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusServiceUnavailable, rec.Code)
+	s.Equal("3600", rec.Header().Get("Retry-After"))
+}
+
+func (s *HandlerSuite) TestBulkheadFull() {
+	tg := &mockTemperatureGetter{err: fmt.Errorf("doing request: %w", domain.ErrBulkheadFull{Name: "wttr"})}
+	h := serviceb.NewHandler(&mockAddressGetter{address: domain.Address{City: "São Paulo"}}, nil, tg, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusServiceUnavailable, rec.Code)
+	s.JSONEq(`{"error":"doing request: bulkhead of wttr is full"}`, rec.Body.String())
+}
+
+func (s *HandlerSuite) TestUntrustedCertificate() {
+	tg := &mockTemperatureGetter{err: domain.ErrUntrustedCertificate{Name: "wttr", Err: errors.New("unknown authority")}}
+	h := serviceb.NewHandler(&mockAddressGetter{address: domain.Address{City: "São Paulo"}}, nil, tg, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusBadGateway, rec.Code)
+	s.JSONEq(`{"error":"untrusted certificate of wttr: unknown authority"}`, rec.Body.String())
+}
+
+func (s *HandlerSuite) TestDeadlineExceeded() {
+	ag := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+	trusted := func(*http.Request) bool { return true }
+	h := serviceb.NewHandler(ag, nil, blockingTemperatureGetter{}, &mockForecastGetter{}, deadline.Middleware(time.Hour, trusted))
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
+	req.Header.Set(deadline.Header, "20")
+
+	start := time.Now()
+	h.ServeHTTP(rec, req)
+
+	s.Less(time.Since(start), time.Second)
+	s.Equal(http.StatusGatewayTimeout, rec.Code)
+	s.JSONEq(`{"error":"request deadline exceeded"}`, rec.Body.String())
+}
+
 func (s *HandlerSuite) TestSuccessfulResponse() {
-	ag := &mockAddressGetter{address: "São Paulo"}
+	ag := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
 	tg := &mockTemperatureGetter{temp: 25.0}
-	h := serviceb.NewHandler(ag, tg)
+	h := serviceb.NewHandler(ag, nil, tg, &mockForecastGetter{})
 	rec := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
 
@@ -100,3 +209,178 @@ func (s *HandlerSuite) TestSuccessfulResponse() {
 	s.Equal(25.0*1.8+32, resp.TempF)
 	s.Equal(25.0+273, resp.TempK)
 }
+
+func (s *HandlerSuite) TestSuccessAfterError() {
+	ag := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+	h := serviceb.NewHandler(ag, nil, &mockTemperatureGetter{temp: 25.0}, &mockForecastGetter{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"123"}`)))
+	s.Equal(http.StatusUnprocessableEntity, rec.Code)
+
+	// the context, and its emptied errors, are reused by the next request
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`)))
+	s.Equal(http.StatusOK, rec.Code)
+}
+
+func (s *HandlerSuite) TestResponseIncludesAddress() {
+	ag := &mockAddressGetter{address: domain.Address{
+		PostalCode: "01001000",
+		City:       "São Paulo",
+		UF:         "SP",
+		IBGE:       "3550308",
+	}}
+	h := serviceb.NewHandler(ag, nil, &mockTemperatureGetter{temp: 25.0}, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusOK, rec.Code)
+
+	var resp serviceb.Response
+	err := json.NewDecoder(rec.Body).Decode(&resp)
+	s.NoError(err)
+	s.Equal("São Paulo", resp.City)
+	s.Require().NotNil(resp.Address)
+	s.Equal("SP", resp.Address.UF)
+	s.Equal("3550308", resp.Address.IBGE)
+}
+
+func (s *HandlerSuite) TestResponseIncludesObservation() {
+	ag := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+	og := &mockObservationGetter{obs: domain.Observation{
+		TempC:       25.0,
+		FeelsLikeC:  27.0,
+		Humidity:    60,
+		Description: "Ensolarado",
+		Source:      "wttr",
+	}}
+	h := serviceb.NewHandler(ag, nil, og, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusOK, rec.Code)
+
+	var resp serviceb.Response
+	err := json.NewDecoder(rec.Body).Decode(&resp)
+	s.NoError(err)
+	s.Equal(25.0, resp.TempC)
+	s.Require().NotNil(resp.Current)
+	s.Equal(27.0, resp.Current.FeelsLikeC)
+	s.Equal(60.0, resp.Current.Humidity)
+	s.Equal("Ensolarado", resp.Current.Description)
+	s.Equal("wttr", resp.Current.Source)
+}
+
+func (s *HandlerSuite) TestForecast() {
+	ag := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+	fg := &mockForecastGetter{forecast: domain.Forecast{
+		Days: []domain.ForecastDay{{
+			Date: "2025-01-15",
+			MinC: 20,
+			MaxC: 30,
+			AvgC: 25,
+			Hourly: []domain.ForecastHour{
+				{Time: "00:00", TempC: 21},
+				{Time: "03:00", TempC: 20},
+			},
+		}},
+	}}
+	h := serviceb.NewHandler(ag, nil, &mockTemperatureGetter{}, fg)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/forecast", strings.NewReader(`{"cep":"01001000"}`)),
+		httptest.NewRequest(http.MethodGet, "/forecast/01001000", nil),
+	} {
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+
+		s.Equal(http.StatusOK, rec.Code)
+
+		var resp serviceb.ForecastResponse
+		err := json.NewDecoder(rec.Body).Decode(&resp)
+		s.NoError(err)
+		s.Equal("São Paulo", resp.City)
+		s.Require().Len(resp.Days, 1)
+		s.InDelta(30.0*1.8+32, resp.Days[0].MaxF, 1e-9)
+		s.Equal(20.0+273, resp.Days[0].MinK)
+		s.Require().Len(resp.Days[0].Hourly, 2)
+		s.InDelta(21.0*1.8+32, resp.Days[0].Hourly[0].TempF, 1e-9)
+	}
+}
+
+func (s *HandlerSuite) TestForecastInvalidPostalCode() {
+	h := serviceb.NewHandler(&mockAddressGetter{}, nil, &mockTemperatureGetter{}, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/forecast/123", nil)
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusUnprocessableEntity, rec.Code)
+}
+
+func (s *HandlerSuite) TestForecastNotFound() {
+	ag := &mockAddressGetter{err: domain.ErrPostalCodeNotFound}
+	h := serviceb.NewHandler(ag, nil, &mockTemperatureGetter{}, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/forecast/99999999", nil)
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusNotFound, rec.Code)
+}
+
+func (s *HandlerSuite) TestGeocodedLocation() {
+	ag := &mockAddressGetter{address: domain.Address{City: "Bom Jesus", UF: "PI"}}
+	gc := &mockGeocoder{coords: domain.Coordinates{Latitude: -9.07, Longitude: -44.36}}
+	og := &mockObservationGetter{obs: domain.Observation{TempC: 30}}
+	h := serviceb.NewHandler(ag, gc, og, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"64900000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("Bom Jesus", og.location.City)
+	s.Equal("PI", og.location.UF)
+	s.Require().NotNil(og.location.Coordinates)
+	s.Equal(domain.Coordinates{Latitude: -9.07, Longitude: -44.36}, *og.location.Coordinates)
+}
+
+func (s *HandlerSuite) TestGeocoderErrorFallsBackToName() {
+	ag := &mockAddressGetter{address: domain.Address{City: "Bom Jesus", UF: "PI"}}
+	gc := &mockGeocoder{err: errors.New("municipality not found")}
+	og := &mockObservationGetter{obs: domain.Observation{TempC: 30}}
+	h := serviceb.NewHandler(ag, gc, og, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"64900000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal("Bom Jesus", og.location.City)
+	s.Nil(og.location.Coordinates)
+}
+
+func (s *HandlerSuite) TestStaleObservation() {
+	ag := &mockAddressGetter{address: domain.Address{City: "São Paulo"}}
+	og := &mockObservationGetter{obs: domain.Observation{TempC: 25, Stale: true, Age: 10 * time.Minute}}
+	h := serviceb.NewHandler(ag, nil, og, &mockForecastGetter{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/temperature", strings.NewReader(`{"cep":"01001000"}`))
+
+	h.ServeHTTP(rec, req)
+
+	s.Equal(http.StatusOK, rec.Code)
+
+	var resp serviceb.Response
+	err := json.NewDecoder(rec.Body).Decode(&resp)
+	s.NoError(err)
+	s.True(resp.Stale)
+	s.Equal(int64(600), resp.AgeSeconds)
+}
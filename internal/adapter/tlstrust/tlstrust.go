@@ -0,0 +1,180 @@
+// Package tlstrust verifies the certificates of the upstream providers:
+// against the CAs of the system, or an embedded copy of the Mozilla bundle
+// where the system has none, plus extra CA files, and optionally against the
+// public keys pinned for a provider.
+package tlstrust
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	// the scratch image has no CAs of its own
+	_ "golang.org/x/crypto/x509roots/fallback"
+)
+
+// pinPrefix prefixes the base64 SHA-256 digest of a public key in a pin.
+const pinPrefix = "sha256/"
+
+// ErrPinMismatch is returned when no certificate of a verified chain has a
+// pinned public key.
+var ErrPinMismatch = errors.New("no certificate matches the pinned public keys")
+
+// RootCAs returns the CAs of the system with those in the PEM files at
+// caFiles added.
+func RootCAs(caFiles []string) (*x509.CertPool, error) {
+	pool, _, err := RootCAsWithDigest(caFiles)
+	return pool, err
+}
+
+// RootCAsWithDigest is [RootCAs], also returning a digest of the contents
+// of the CA files, which changes whenever any of them is rewritten.
+func RootCAsWithDigest(caFiles []string) (*x509.CertPool, string, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, "", fmt.Errorf("loading system CAs: %w", err)
+	}
+
+	digest := sha256.New()
+	for _, path := range caFiles {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, "", fmt.Errorf("no certificates found in CA file %s", path)
+		}
+		fmt.Fprintf(digest, "%q %x\n", path, sha256.Sum256(b))
+	}
+
+	return pool, base64.StdEncoding.EncodeToString(digest.Sum(nil)), nil
+}
+
+// Config returns the client TLS configuration trusting roots.
+func Config(roots *x509.CertPool) *tls.Config {
+	return &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+}
+
+// WithPins returns a copy of c which, besides the usual verification,
+// requires a certificate of the verified chain, leaf, intermediate or root,
+// to have one of the pinned public keys. Pins are given as "sha256/" and the
+// base64 digest of the DER of the public key, as returned by [Pin]. Pins that
+// can not be parsed match no certificate. Without pins, c is returned as is.
+func WithPins(c *tls.Config, pins []string) *tls.Config {
+	if len(pins) == 0 {
+		return c
+	}
+
+	digests := make(map[[sha256.Size]byte]bool, len(pins))
+	for _, pin := range pins {
+		if d, err := ParsePin(pin); err == nil {
+			digests[d] = true
+		}
+	}
+
+	c = c.Clone()
+	c.VerifyConnection = func(cs tls.ConnectionState) error {
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				if digests[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+					return nil
+				}
+			}
+		}
+		return ErrPinMismatch
+	}
+
+	return c
+}
+
+// Pin returns the pin of the public key of cert.
+func Pin(cert *x509.Certificate) string {
+	d := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(d[:])
+}
+
+// ParsePin returns the digest of the public key in pin.
+func ParsePin(pin string) ([sha256.Size]byte, error) {
+	var d [sha256.Size]byte
+
+	encoded, ok := strings.CutPrefix(pin, pinPrefix)
+	if !ok {
+		return d, fmt.Errorf("pin %q must start with %s", pin, pinPrefix)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(b) != sha256.Size {
+		return d, fmt.Errorf("pin %q must hold the base64 of a SHA-256 digest", pin)
+	}
+	copy(d[:], b)
+
+	return d, nil
+}
+
+// Transport is an [http.RoundTripper] reporting the certificates of a
+// provider that fail verification.
+type Transport struct {
+	next      http.RoundTripper
+	name      string
+	attrs     metric.MeasurementOption
+	untrusted metric.Int64Counter
+}
+
+// NewTransport returns a [Transport] calling the provider name through next,
+// whose TLS configuration comes from this package.
+func NewTransport(next http.RoundTripper, name string) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	untrusted, _ := otel.Meter("service-b").Int64Counter(
+		"tls.untrusted_certificates",
+		metric.WithDescription("Calls to a provider whose certificate failed verification"),
+	)
+
+	return &Transport{
+		next:      next,
+		name:      name,
+		attrs:     metric.WithAttributes(attribute.String("provider", name)),
+		untrusted: untrusted,
+	}
+}
+
+// RoundTrip implements [http.RoundTripper]. Certificates failing verification
+// are logged and fail the call with [domain.ErrUntrustedCertificate].
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err == nil || !untrusted(err) {
+		return res, err
+	}
+
+	ctx := req.Context()
+	err = domain.ErrUntrustedCertificate{Name: t.name, Err: err}
+
+	log.Printf("%s: %s", req.URL.Host, err)
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	t.untrusted.Add(ctx, 1, t.attrs)
+
+	return nil, err
+}
+
+func untrusted(err error) bool {
+	var verification *tls.CertificateVerificationError
+	return errors.As(err, &verification) || errors.Is(err, ErrPinMismatch)
+}
@@ -0,0 +1,126 @@
+package tlstrust_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/tlstrust"
+)
+
+type TLSTrustSuite struct {
+	suite.Suite
+	server *httptest.Server
+	caFile string
+}
+
+func TestTLSTrustSuite(t *testing.T) {
+	suite.Run(t, new(TLSTrustSuite))
+}
+
+func (s *TLSTrustSuite) SetupTest() {
+	s.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	s.caFile = filepath.Join(s.T().TempDir(), "ca.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.server.Certificate().Raw})
+	s.Require().NoError(os.WriteFile(s.caFile, b, 0o600))
+}
+
+func (s *TLSTrustSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// get calls the test server with the CAs in caFiles and the given pins.
+func (s *TLSTrustSuite) get(caFiles []string, pins ...string) error {
+	roots, err := tlstrust.RootCAs(caFiles)
+	s.Require().NoError(err)
+
+	cl := &http.Client{Transport: tlstrust.NewTransport(&http.Transport{
+		TLSClientConfig: tlstrust.WithPins(tlstrust.Config(roots), pins),
+	}, "test")}
+
+	res, err := cl.Get(s.server.URL)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	return nil
+}
+
+func (s *TLSTrustSuite) TestTrustedCAFile() {
+	s.NoError(s.get([]string{s.caFile}))
+}
+
+func (s *TLSTrustSuite) TestUnknownAuthority() {
+	err := s.get(nil)
+
+	var untrusted domain.ErrUntrustedCertificate
+	s.Require().ErrorAs(err, &untrusted)
+	s.Equal("test", untrusted.Name)
+}
+
+func (s *TLSTrustSuite) TestMatchingPin() {
+	pin := tlstrust.Pin(s.server.Certificate())
+
+	s.NoError(s.get([]string{s.caFile}, "sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", pin))
+}
+
+func (s *TLSTrustSuite) TestMismatchingPin() {
+	err := s.get([]string{s.caFile}, "sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+
+	s.Require().ErrorAs(err, new(domain.ErrUntrustedCertificate))
+	s.ErrorIs(err, tlstrust.ErrPinMismatch)
+}
+
+func (s *TLSTrustSuite) TestInvalidPinMatchesNothing() {
+	err := s.get([]string{s.caFile}, "md5/whatever")
+
+	s.ErrorIs(err, tlstrust.ErrPinMismatch)
+}
+
+func (s *TLSTrustSuite) TestInvalidCAFile() {
+	path := filepath.Join(s.T().TempDir(), "empty.pem")
+	s.Require().NoError(os.WriteFile(path, []byte("not a certificate"), 0o600))
+
+	_, err := tlstrust.RootCAs([]string{path})
+	s.ErrorContains(err, "no certificates found")
+
+	_, err = tlstrust.RootCAs([]string{filepath.Join(s.T().TempDir(), "missing.pem")})
+	s.ErrorIs(err, os.ErrNotExist)
+}
+
+func (s *TLSTrustSuite) TestDigestFollowsContents() {
+	_, before, err := tlstrust.RootCAsWithDigest([]string{s.caFile})
+	s.Require().NoError(err)
+
+	_, same, err := tlstrust.RootCAsWithDigest([]string{s.caFile})
+	s.Require().NoError(err)
+	s.Equal(before, same)
+
+	b, err := os.ReadFile(s.caFile)
+	s.Require().NoError(err)
+	s.Require().NoError(os.WriteFile(s.caFile, append(b, b...), 0o600))
+
+	_, after, err := tlstrust.RootCAsWithDigest([]string{s.caFile})
+	s.Require().NoError(err)
+	s.NotEqual(before, after)
+}
+
+func (s *TLSTrustSuite) TestParsePin() {
+	_, err := tlstrust.ParsePin(tlstrust.Pin(s.server.Certificate()))
+	s.NoError(err)
+
+	_, err = tlstrust.ParsePin("sha1/AAAA")
+	s.ErrorContains(err, "must start with sha256/")
+
+	_, err = tlstrust.ParsePin("sha256/AAAA")
+	s.ErrorContains(err, "SHA-256 digest")
+}
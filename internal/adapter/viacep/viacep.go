@@ -12,7 +12,7 @@ import (
 	"go.opentelemetry.io/otel"
 )
 
-const baseURL = "http://viacep.com.br/ws/"
+const baseURL = "https://viacep.com.br/ws/"
 
 // ErrStatusCode TODO
 type ErrStatusCode struct {
@@ -35,41 +35,41 @@ func NewAddressGetter(cl *http.Client) domain.AddressGetter {
 }
 
 // GetAddress implements [domain.AddressGetter].
-func (a *ViaCEP) GetAddress(ctx context.Context, postalCode string) (string, error) {
+func (a *ViaCEP) GetAddress(ctx context.Context, postalCode string) (domain.Address, error) {
 	ctx, span := otel.Tracer("service-b").Start(ctx, "get-address")
 	defer span.End()
 
 	u, err := a.getURL(postalCode)
 	if err != nil {
-		return "", fmt.Errorf("mounting url: %w", err)
+		return domain.Address{}, fmt.Errorf("mounting url: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+		return domain.Address{}, fmt.Errorf("creating request: %w", err)
 	}
 
 	res, err := a.cl.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("doing request: %w", err)
+		return domain.Address{}, fmt.Errorf("doing request: %w", err)
 	}
 
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return "", ErrStatusCode{Status: res.StatusCode}
+		return domain.Address{}, ErrStatusCode{Status: res.StatusCode}
 	}
 
 	var body viaCEP
 	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
-		return "", fmt.Errorf("decoding response: %w", err)
+		return domain.Address{}, fmt.Errorf("decoding response: %w", err)
 	}
 
 	if body.Erro != "" {
-		return "", domain.ErrPostalCodeNotFound
+		return domain.Address{}, domain.ErrPostalCodeNotFound
 	}
 
-	return body.Localidade, nil
+	return body.toAddress(postalCode), nil
 }
 
 func (a *ViaCEP) getURL(postalCode string) (string, error) {
@@ -92,3 +92,23 @@ type viaCEP struct {
 	Ddd         string `json:"ddd"`
 	Siafi       string `json:"siafi"`
 }
+
+func (v viaCEP) toAddress(postalCode string) domain.Address {
+	cep := domain.NormalizePostalCode(v.Cep)
+	if cep == "" {
+		cep = domain.NormalizePostalCode(postalCode)
+	}
+
+	return domain.Address{
+		PostalCode:   cep,
+		Street:       v.Logradouro,
+		Neighborhood: v.Bairro,
+		City:         v.Localidade,
+		UF:           v.Uf,
+		State:        v.Estado,
+		Region:       v.Regiao,
+		IBGE:         v.Ibge,
+		DDD:          v.Ddd,
+		Source:       "viacep",
+	}
+}
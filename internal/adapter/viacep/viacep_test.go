@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
 	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/viacep"
 )
 
@@ -75,7 +76,11 @@ func (s *ViaCEPSuite) TestInvalidJSON() {
 
 func (s *ViaCEPSuite) TestSuccessfulAddress() {
 	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
-		body := `{"localidade":"São Paulo"}`
+		s.Equal("https://viacep.com.br/ws/01001000/json", r.URL.String())
+
+		body := `{"cep":"01001-000","logradouro":"Praça da Sé","bairro":"Sé",` +
+			`"localidade":"São Paulo","uf":"SP","estado":"São Paulo",` +
+			`"regiao":"Sudeste","ibge":"3550308","ddd":"11"}`
 		return &http.Response{
 			StatusCode: http.StatusOK,
 			Body:       io.NopCloser(strings.NewReader(body)),
@@ -86,5 +91,31 @@ func (s *ViaCEPSuite) TestSuccessfulAddress() {
 	addr, err := ag.GetAddress(context.Background(), "01001000")
 
 	s.NoError(err)
-	s.Equal("São Paulo", addr)
+	s.Equal(domain.Address{
+		PostalCode:   "01001000",
+		Street:       "Praça da Sé",
+		Neighborhood: "Sé",
+		City:         "São Paulo",
+		UF:           "SP",
+		State:        "São Paulo",
+		Region:       "Sudeste",
+		IBGE:         "3550308",
+		DDD:          "11",
+		Source:       "viacep",
+	}, addr)
+}
+
+func (s *ViaCEPSuite) TestPostalCodeNotFound() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"erro":"true"}`)),
+		}, nil
+	})
+
+	ag := viacep.NewAddressGetter(s.newGetter(rt))
+	addr, err := ag.GetAddress(context.Background(), "99999999")
+
+	s.ErrorIs(err, domain.ErrPostalCodeNotFound)
+	s.Empty(addr)
 }
@@ -0,0 +1,171 @@
+// Package weatherapi implements [domain.TemperatureGetter] using
+// WeatherAPI.com.
+package weatherapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	baseURL = "https://api.weatherapi.com/v1/current.json"
+
+	// codeLocationNotFound is the WeatherAPI.com error code for queries that
+	// match no location.
+	codeLocationNotFound = 1006
+)
+
+// ErrLocationNotFound is returned when WeatherAPI.com has no match for the
+// location. It is [domain.ErrLocationNotFound].
+var ErrLocationNotFound = domain.ErrLocationNotFound
+
+// ErrAPI is returned when WeatherAPI.com answers with an error. Code is the
+// WeatherAPI.com error code (e.g. 2006 for an invalid key, 2007 for an
+// exceeded quota).
+type ErrAPI struct {
+	Status  int
+	Code    int
+	Message string
+}
+
+// Error implements [error].
+func (e ErrAPI) Error() string {
+	return fmt.Sprintf("unexpected status code %d: error %d: %s", e.Status, e.Code, e.Message)
+}
+
+// WeatherAPI is a [domain.TemperatureGetter] backed by WeatherAPI.com.
+type WeatherAPI struct {
+	cl  *http.Client
+	key string
+}
+
+// NewTemperatureGetter returns a new implementation of
+// [domain.TemperatureGetter] authenticated with apiKey.
+func NewTemperatureGetter(cl *http.Client, apiKey string) domain.TemperatureGetter {
+	return &WeatherAPI{cl: cl, key: apiKey}
+}
+
+// GetTemperature implements [domain.TemperatureGetter].
+func (w *WeatherAPI) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := w.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter].
+func (w *WeatherAPI) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "get-temperature")
+	defer span.End()
+
+	u, err := w.getURL(location)
+	if err != nil {
+		return domain.Observation{}, fmt.Errorf("mounting url: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return domain.Observation{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := w.cl.Do(req)
+	if err != nil {
+		return domain.Observation{}, fmt.Errorf("doing request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var body errorBody
+		_ = json.NewDecoder(res.Body).Decode(&body)
+		if body.Error.Code == codeLocationNotFound {
+			return domain.Observation{}, ErrLocationNotFound
+		}
+		return domain.Observation{}, ErrAPI{
+			Status:  res.StatusCode,
+			Code:    body.Error.Code,
+			Message: body.Error.Message,
+		}
+	}
+
+	var body weatherAPI
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return domain.Observation{}, fmt.Errorf("decoding response: %w", err)
+	}
+
+	return body.Current.toObservation(), nil
+}
+
+func (w *WeatherAPI) getURL(location domain.Location) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parsing URL: %w", err)
+	}
+
+	u.RawQuery = url.Values{"key": {w.key}, "q": {location.String()}, "lang": {"pt"}}.Encode()
+
+	return u.String(), nil
+}
+
+type errorBody struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type weatherAPI struct {
+	Current current `json:"current"`
+}
+
+type current struct {
+	LastUpdatedEpoch int64   `json:"last_updated_epoch"`
+	TempC            float64 `json:"temp_c"`
+	FeelslikeC       float64 `json:"feelslike_c"`
+	Humidity         float64 `json:"humidity"`
+	PressureMb       float64 `json:"pressure_mb"`
+	WindKph          float64 `json:"wind_kph"`
+	WindDegree       float64 `json:"wind_degree"`
+	WindDir          string  `json:"wind_dir"`
+	Uv               float64 `json:"uv"`
+	VisKm            float64 `json:"vis_km"`
+	Cloud            float64 `json:"cloud"`
+	PrecipMm         float64 `json:"precip_mm"`
+	Condition        struct {
+		Text string `json:"text"`
+		Code int    `json:"code"`
+	} `json:"condition"`
+}
+
+func (c current) toObservation() domain.Observation {
+	var observedAt time.Time
+	if c.LastUpdatedEpoch != 0 {
+		observedAt = time.Unix(c.LastUpdatedEpoch, 0).UTC()
+	}
+
+	return domain.Observation{
+		TempC:         c.TempC,
+		FeelsLikeC:    c.FeelslikeC,
+		Humidity:      c.Humidity,
+		PressureHPa:   c.PressureMb,
+		WindSpeedKmph: c.WindKph,
+		WindDirDegree: c.WindDegree,
+		WindDir:       c.WindDir,
+		UVIndex:       c.Uv,
+		VisibilityKm:  c.VisKm,
+		CloudCover:    c.Cloud,
+		PrecipMM:      c.PrecipMm,
+		Description:   c.Condition.Text,
+		ObservedAt:    observedAt,
+		Source:        "weatherapi",
+	}
+}
@@ -0,0 +1,136 @@
+package weatherapi_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/weatherapi"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+type WeatherAPISuite struct {
+	suite.Suite
+}
+
+func TestWeatherAPISuite(t *testing.T) {
+	suite.Run(t, new(WeatherAPISuite))
+}
+
+func (s *WeatherAPISuite) newGetter(rt roundTripperFunc) *http.Client {
+	return &http.Client{Transport: rt}
+}
+
+func (s *WeatherAPISuite) TestFailedRequest() {
+	expectedErr := errors.New("request failed")
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, expectedErr
+	})
+
+	tg := weatherapi.NewTemperatureGetter(s.newGetter(rt), "key")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.ErrorIs(err, expectedErr)
+	s.Zero(temp)
+}
+
+func (s *WeatherAPISuite) TestAPIError() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusForbidden,
+			Body: io.NopCloser(strings.NewReader(
+				`{"error":{"code":2007,"message":"API key has exceeded calls per month quota."}}`,
+			)),
+		}, nil
+	})
+
+	tg := weatherapi.NewTemperatureGetter(s.newGetter(rt), "key")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	var apiErr weatherapi.ErrAPI
+	s.ErrorAs(err, &apiErr)
+	s.Equal(http.StatusForbidden, apiErr.Status)
+	s.Equal(2007, apiErr.Code)
+	s.Zero(temp)
+}
+
+func (s *WeatherAPISuite) TestLocationNotFound() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body: io.NopCloser(strings.NewReader(
+				`{"error":{"code":1006,"message":"No matching location found."}}`,
+			)),
+		}, nil
+	})
+
+	tg := weatherapi.NewTemperatureGetter(s.newGetter(rt), "key")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "Nowhere"})
+
+	s.ErrorIs(err, weatherapi.ErrLocationNotFound)
+	s.Zero(temp)
+}
+
+func (s *WeatherAPISuite) TestInvalidJSON() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader("{]")),
+		}, nil
+	})
+
+	tg := weatherapi.NewTemperatureGetter(s.newGetter(rt), "key")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.Error(err)
+	s.Zero(temp)
+}
+
+func (s *WeatherAPISuite) TestSuccessfulObservation() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		s.Equal("secret", r.URL.Query().Get("key"))
+		s.Equal("São Paulo, SP", r.URL.Query().Get("q"))
+
+		body := `{"current":{"last_updated_epoch":1736949600,"temp_c":25.0,` +
+			`"feelslike_c":27.0,"humidity":60,"pressure_mb":1015.0,` +
+			`"wind_kph":11.2,"wind_degree":120,"wind_dir":"ESE","uv":6.0,` +
+			`"vis_km":10.0,"cloud":25,"precip_mm":0.1,` +
+			`"condition":{"text":"Parcialmente nublado","code":1003}}}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	og := weatherapi.NewTemperatureGetter(s.newGetter(rt), "secret").(domain.ObservationGetter)
+	obs, err := og.GetObservation(context.Background(), domain.Location{City: "São Paulo", UF: "SP"})
+
+	s.NoError(err)
+	s.Equal(domain.Observation{
+		TempC:         25,
+		FeelsLikeC:    27,
+		Humidity:      60,
+		PressureHPa:   1015,
+		WindSpeedKmph: 11.2,
+		WindDirDegree: 120,
+		WindDir:       "ESE",
+		UVIndex:       6,
+		VisibilityKm:  10,
+		CloudCover:    25,
+		PrecipMM:      0.1,
+		Description:   "Parcialmente nublado",
+		ObservedAt:    time.Date(2025, 1, 15, 14, 0, 0, 0, time.UTC),
+		Source:        "weatherapi",
+	}, obs)
+}
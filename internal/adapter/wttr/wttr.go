@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
 	"go.opentelemetry.io/otel"
@@ -16,8 +17,13 @@ import (
 
 const baseURL = "https://wttr.in/"
 
-// ErrNoConditionFound TODO
-var ErrNoConditionFound = errors.New("no condition found")
+var (
+	// ErrNoConditionFound TODO
+	ErrNoConditionFound = errors.New("no condition found")
+	// ErrNoForecastFound is returned when wttr answers without any forecast
+	// day.
+	ErrNoForecastFound = errors.New("no forecast found")
+)
 
 // ErrStatusCode TODO
 type ErrStatusCode struct {
@@ -39,52 +45,92 @@ func NewTemperatureGetter(cl *http.Client) domain.TemperatureGetter {
 	return &Wttr{cl: cl}
 }
 
+// NewForecastGetter returns a new implementation of [domain.ForecastGetter].
+func NewForecastGetter(cl *http.Client) domain.ForecastGetter {
+	return &Wttr{cl: cl}
+}
+
 // GetTemperature implements [domain.TemperatureGetter].
-func (w *Wttr) GetTemperature(ctx context.Context, location string) (float64, error) {
+func (w *Wttr) GetTemperature(ctx context.Context, location domain.Location) (float64, error) {
+	obs, err := w.GetObservation(ctx, location)
+	if err != nil {
+		return 0, err
+	}
+
+	return obs.TempC, nil
+}
+
+// GetObservation implements [domain.ObservationGetter].
+func (w *Wttr) GetObservation(ctx context.Context, location domain.Location) (domain.Observation, error) {
 	ctx, span := otel.Tracer("service-b").Start(ctx, "get-temperature")
 	defer span.End()
 
+	body, err := w.fetch(ctx, location)
+	if err != nil {
+		return domain.Observation{}, err
+	}
+
+	if len(body.CurrentCondition) == 0 {
+		return domain.Observation{}, ErrNoConditionFound
+	}
+
+	return body.CurrentCondition[0].toObservation()
+}
+
+// GetForecast implements [domain.ForecastGetter].
+func (w *Wttr) GetForecast(ctx context.Context, location domain.Location) (domain.Forecast, error) {
+	ctx, span := otel.Tracer("service-b").Start(ctx, "get-forecast")
+	defer span.End()
+
+	body, err := w.fetch(ctx, location)
+	if err != nil {
+		return domain.Forecast{}, err
+	}
+
+	if len(body.Weather) == 0 {
+		return domain.Forecast{}, ErrNoForecastFound
+	}
+
+	days := make([]domain.ForecastDay, 0, len(body.Weather))
+	for _, day := range body.Weather {
+		days = append(days, day.toForecastDay())
+	}
+
+	return domain.Forecast{Days: days, Source: "wttr"}, nil
+}
+
+func (w *Wttr) fetch(ctx context.Context, location domain.Location) (wttr, error) {
 	u, err := w.getURL(location)
 	if err != nil {
-		return 0, fmt.Errorf("mounting url: %w", err)
+		return wttr{}, fmt.Errorf("mounting url: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
-		return 0, fmt.Errorf("creating request: %w", err)
+		return wttr{}, fmt.Errorf("creating request: %w", err)
 	}
 
 	res, err := w.cl.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("doing request: %w", err)
+		return wttr{}, fmt.Errorf("doing request: %w", err)
 	}
 
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return 0, ErrStatusCode{Status: res.StatusCode}
+		return wttr{}, ErrStatusCode{Status: res.StatusCode}
 	}
 
 	var body wttr
 	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
-		return 0, fmt.Errorf("decoding response: %w", err)
+		return wttr{}, fmt.Errorf("decoding response: %w", err)
 	}
 
-	if len(body.CurrentCondition) == 0 {
-		return 0, ErrNoConditionFound
-	}
-
-	c, err := strconv.ParseFloat(body.CurrentCondition[0].TempC, 64)
-	if err != nil {
-		return 0, fmt.Errorf("converting temperature number: %w", err)
-	}
-
-	return c, nil
-
+	return body, nil
 }
 
-func (w *Wttr) getURL(location string) (string, error) {
-	b, err := url.JoinPath(baseURL, location)
+func (w *Wttr) getURL(location domain.Location) (string, error) {
+	b, err := url.JoinPath(baseURL, location.String())
 	if err != nil {
 		return "", fmt.Errorf("joining location: %w", err)
 	}
@@ -94,47 +140,14 @@ func (w *Wttr) getURL(location string) (string, error) {
 		return "", fmt.Errorf("parsing URL: %w", err)
 	}
 
-	u.RawQuery = url.Values{"format": {"j1"}}.Encode()
-
-	// u.Query().Add("json", "j1")
+	u.RawQuery = url.Values{"format": {"j1"}, "lang": {"pt"}}.Encode()
 
 	return u.String(), nil
-
 }
 
 type wttr struct {
-	CurrentCondition []struct {
-		FeelsLikeC string `json:"FeelsLikeC"`
-		FeelsLikeF string `json:"FeelsLikeF"`
-		Cloudcover string `json:"cloudcover"`
-		Humidity   string `json:"humidity"`
-		LangPt     []struct {
-			Value string `json:"value"`
-		} `json:"lang_pt"`
-		LocalObsDateTime string `json:"localObsDateTime"`
-		ObservationTime  string `json:"observation_time"`
-		PrecipInches     string `json:"precipInches"`
-		PrecipMm         string `json:"precipMM"`
-		Pressure         string `json:"pressure"`
-		PressureInches   string `json:"pressureInches"`
-		TempC            string `json:"temp_C"`
-		TempF            string `json:"temp_F"`
-		UvIndex          string `json:"uvIndex"`
-		Visibility       string `json:"visibility"`
-		VisibilityMiles  string `json:"visibilityMiles"`
-		WeatherCode      string `json:"weatherCode"`
-		WeatherDesc      []struct {
-			Value string `json:"value"`
-		} `json:"weatherDesc"`
-		WeatherIconURL []struct {
-			Value string `json:"value"`
-		} `json:"weatherIconUrl"`
-		Winddir16Point string `json:"winddir16Point"`
-		WinddirDegree  string `json:"winddirDegree"`
-		WindspeedKmph  string `json:"windspeedKmph"`
-		WindspeedMiles string `json:"windspeedMiles"`
-	} `json:"current_condition"`
-	NearestArea []struct {
+	CurrentCondition []currentCondition `json:"current_condition"`
+	NearestArea      []struct {
 		AreaName []struct {
 			Value string `json:"value"`
 		} `json:"areaName"`
@@ -155,74 +168,223 @@ type wttr struct {
 		Query string `json:"query"`
 		Type  string `json:"type"`
 	} `json:"request"`
-	Weather []struct {
-		Astronomy []struct {
-			MoonIllumination string `json:"moon_illumination"`
-			MoonPhase        string `json:"moon_phase"`
-			Moonrise         string `json:"moonrise"`
-			Moonset          string `json:"moonset"`
-			Sunrise          string `json:"sunrise"`
-			Sunset           string `json:"sunset"`
-		} `json:"astronomy"`
-		AvgtempC string `json:"avgtempC"`
-		AvgtempF string `json:"avgtempF"`
-		Date     string `json:"date"`
-		Hourly   []struct {
-			DewPointC        string `json:"DewPointC"`
-			DewPointF        string `json:"DewPointF"`
-			FeelsLikeC       string `json:"FeelsLikeC"`
-			FeelsLikeF       string `json:"FeelsLikeF"`
-			HeatIndexC       string `json:"HeatIndexC"`
-			HeatIndexF       string `json:"HeatIndexF"`
-			WindChillC       string `json:"WindChillC"`
-			WindChillF       string `json:"WindChillF"`
-			WindGustKmph     string `json:"WindGustKmph"`
-			WindGustMiles    string `json:"WindGustMiles"`
-			Chanceoffog      string `json:"chanceoffog"`
-			Chanceoffrost    string `json:"chanceoffrost"`
-			Chanceofhightemp string `json:"chanceofhightemp"`
-			Chanceofovercast string `json:"chanceofovercast"`
-			Chanceofrain     string `json:"chanceofrain"`
-			Chanceofremdry   string `json:"chanceofremdry"`
-			Chanceofsnow     string `json:"chanceofsnow"`
-			Chanceofsunshine string `json:"chanceofsunshine"`
-			Chanceofthunder  string `json:"chanceofthunder"`
-			Chanceofwindy    string `json:"chanceofwindy"`
-			Cloudcover       string `json:"cloudcover"`
-			DiffRad          string `json:"diffRad"`
-			Humidity         string `json:"humidity"`
-			LangPt           []struct {
-				Value string `json:"value"`
-			} `json:"lang_pt"`
-			PrecipInches    string `json:"precipInches"`
-			PrecipMm        string `json:"precipMM"`
-			Pressure        string `json:"pressure"`
-			PressureInches  string `json:"pressureInches"`
-			ShortRad        string `json:"shortRad"`
-			TempC           string `json:"tempC"`
-			TempF           string `json:"tempF"`
-			Time            string `json:"time"`
-			UvIndex         string `json:"uvIndex"`
-			Visibility      string `json:"visibility"`
-			VisibilityMiles string `json:"visibilityMiles"`
-			WeatherCode     string `json:"weatherCode"`
-			WeatherDesc     []struct {
-				Value string `json:"value"`
-			} `json:"weatherDesc"`
-			WeatherIconURL []struct {
-				Value string `json:"value"`
-			} `json:"weatherIconUrl"`
-			Winddir16Point string `json:"winddir16Point"`
-			WinddirDegree  string `json:"winddirDegree"`
-			WindspeedKmph  string `json:"windspeedKmph"`
-			WindspeedMiles string `json:"windspeedMiles"`
-		} `json:"hourly"`
-		MaxtempC    string `json:"maxtempC"`
-		MaxtempF    string `json:"maxtempF"`
-		MintempC    string `json:"mintempC"`
-		MintempF    string `json:"mintempF"`
-		SunHour     string `json:"sunHour"`
-		TotalSnowCm string `json:"totalSnow_cm"`
-		UvIndex     string `json:"uvIndex"`
-	} `json:"weather"`
+	Weather []weather `json:"weather"`
+}
+
+type currentCondition struct {
+	FeelsLikeC string `json:"FeelsLikeC"`
+	FeelsLikeF string `json:"FeelsLikeF"`
+	Cloudcover string `json:"cloudcover"`
+	Humidity   string `json:"humidity"`
+	LangPt     []struct {
+		Value string `json:"value"`
+	} `json:"lang_pt"`
+	LocalObsDateTime string `json:"localObsDateTime"`
+	ObservationTime  string `json:"observation_time"`
+	PrecipInches     string `json:"precipInches"`
+	PrecipMm         string `json:"precipMM"`
+	Pressure         string `json:"pressure"`
+	PressureInches   string `json:"pressureInches"`
+	TempC            string `json:"temp_C"`
+	TempF            string `json:"temp_F"`
+	UvIndex          string `json:"uvIndex"`
+	Visibility       string `json:"visibility"`
+	VisibilityMiles  string `json:"visibilityMiles"`
+	WeatherCode      string `json:"weatherCode"`
+	WeatherDesc      []struct {
+		Value string `json:"value"`
+	} `json:"weatherDesc"`
+	WeatherIconURL []struct {
+		Value string `json:"value"`
+	} `json:"weatherIconUrl"`
+	Winddir16Point string `json:"winddir16Point"`
+	WinddirDegree  string `json:"winddirDegree"`
+	WindspeedKmph  string `json:"windspeedKmph"`
+	WindspeedMiles string `json:"windspeedMiles"`
+}
+
+func (c currentCondition) toObservation() (domain.Observation, error) {
+	temp, err := strconv.ParseFloat(c.TempC, 64)
+	if err != nil {
+		return domain.Observation{}, fmt.Errorf("converting temperature number: %w", err)
+	}
+
+	return domain.Observation{
+		TempC:         temp,
+		FeelsLikeC:    parseOptional(c.FeelsLikeC),
+		Humidity:      parseOptional(c.Humidity),
+		PressureHPa:   parseOptional(c.Pressure),
+		WindSpeedKmph: parseOptional(c.WindspeedKmph),
+		WindDirDegree: parseOptional(c.WinddirDegree),
+		WindDir:       c.Winddir16Point,
+		UVIndex:       parseOptional(c.UvIndex),
+		VisibilityKm:  parseOptional(c.Visibility),
+		CloudCover:    parseOptional(c.Cloudcover),
+		PrecipMM:      parseOptional(c.PrecipMm),
+		Description:   c.description(),
+		ObservedAt:    c.observedAt(),
+		Source:        "wttr",
+	}, nil
+}
+
+func (c currentCondition) description() string {
+	if len(c.LangPt) > 0 && c.LangPt[0].Value != "" {
+		return c.LangPt[0].Value
+	}
+	if len(c.WeatherDesc) > 0 {
+		return c.WeatherDesc[0].Value
+	}
+	return ""
+}
+
+// observedAt combines the date of localObsDateTime (local to the location)
+// with observation_time (UTC, without a date). The date is shifted by a day
+// when the two are too far apart to be the same instant.
+func (c currentCondition) observedAt() time.Time {
+	local, err := time.Parse("2006-01-02 03:04 PM", c.LocalObsDateTime)
+	if err != nil {
+		return time.Time{}
+	}
+
+	clock, err := time.Parse("03:04 PM", c.ObservationTime)
+	if err != nil {
+		return time.Time{}
+	}
+
+	utc := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+
+	switch offset := local.Sub(utc); {
+	case offset > 14*time.Hour:
+		utc = utc.Add(24 * time.Hour)
+	case offset < -14*time.Hour:
+		utc = utc.Add(-24 * time.Hour)
+	}
+
+	return utc
+}
+
+func (d weather) toForecastDay() domain.ForecastDay {
+	hours := make([]domain.ForecastHour, 0, len(d.Hourly))
+	for _, h := range d.Hourly {
+		hours = append(hours, h.toForecastHour())
+	}
+
+	return domain.ForecastDay{
+		Date:   d.Date,
+		MinC:   parseOptional(d.MintempC),
+		MaxC:   parseOptional(d.MaxtempC),
+		AvgC:   parseOptional(d.AvgtempC),
+		Hourly: hours,
+	}
+}
+
+func (h hourly) toForecastHour() domain.ForecastHour {
+	var desc string
+	if len(h.LangPt) > 0 && h.LangPt[0].Value != "" {
+		desc = h.LangPt[0].Value
+	} else if len(h.WeatherDesc) > 0 {
+		desc = h.WeatherDesc[0].Value
+	}
+
+	return domain.ForecastHour{
+		Time:         h.clock(),
+		TempC:        parseOptional(h.TempC),
+		FeelsLikeC:   parseOptional(h.FeelsLikeC),
+		Humidity:     parseOptional(h.Humidity),
+		ChanceOfRain: parseOptional(h.Chanceofrain),
+		PrecipMM:     parseOptional(h.PrecipMm),
+		Description:  desc,
+	}
+}
+
+// clock converts wttr's hourly time ("0", "300", ..., "2100") to HH:MM.
+func (h hourly) clock() string {
+	n, err := strconv.Atoi(h.Time)
+	if err != nil {
+		return h.Time
+	}
+	return fmt.Sprintf("%02d:%02d", n/100, n%100)
+}
+
+// parseOptional parses the numeric strings wttr uses for every field,
+// returning zero for fields that are missing or malformed.
+func parseOptional(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+type weather struct {
+	Astronomy []struct {
+		MoonIllumination string `json:"moon_illumination"`
+		MoonPhase        string `json:"moon_phase"`
+		Moonrise         string `json:"moonrise"`
+		Moonset          string `json:"moonset"`
+		Sunrise          string `json:"sunrise"`
+		Sunset           string `json:"sunset"`
+	} `json:"astronomy"`
+	AvgtempC    string   `json:"avgtempC"`
+	AvgtempF    string   `json:"avgtempF"`
+	Date        string   `json:"date"`
+	Hourly      []hourly `json:"hourly"`
+	MaxtempC    string   `json:"maxtempC"`
+	MaxtempF    string   `json:"maxtempF"`
+	MintempC    string   `json:"mintempC"`
+	MintempF    string   `json:"mintempF"`
+	SunHour     string   `json:"sunHour"`
+	TotalSnowCm string   `json:"totalSnow_cm"`
+	UvIndex     string   `json:"uvIndex"`
+}
+
+type hourly struct {
+	DewPointC        string `json:"DewPointC"`
+	DewPointF        string `json:"DewPointF"`
+	FeelsLikeC       string `json:"FeelsLikeC"`
+	FeelsLikeF       string `json:"FeelsLikeF"`
+	HeatIndexC       string `json:"HeatIndexC"`
+	HeatIndexF       string `json:"HeatIndexF"`
+	WindChillC       string `json:"WindChillC"`
+	WindChillF       string `json:"WindChillF"`
+	WindGustKmph     string `json:"WindGustKmph"`
+	WindGustMiles    string `json:"WindGustMiles"`
+	Chanceoffog      string `json:"chanceoffog"`
+	Chanceoffrost    string `json:"chanceoffrost"`
+	Chanceofhightemp string `json:"chanceofhightemp"`
+	Chanceofovercast string `json:"chanceofovercast"`
+	Chanceofrain     string `json:"chanceofrain"`
+	Chanceofremdry   string `json:"chanceofremdry"`
+	Chanceofsnow     string `json:"chanceofsnow"`
+	Chanceofsunshine string `json:"chanceofsunshine"`
+	Chanceofthunder  string `json:"chanceofthunder"`
+	Chanceofwindy    string `json:"chanceofwindy"`
+	Cloudcover       string `json:"cloudcover"`
+	DiffRad          string `json:"diffRad"`
+	Humidity         string `json:"humidity"`
+	LangPt           []struct {
+		Value string `json:"value"`
+	} `json:"lang_pt"`
+	PrecipInches    string `json:"precipInches"`
+	PrecipMm        string `json:"precipMM"`
+	Pressure        string `json:"pressure"`
+	PressureInches  string `json:"pressureInches"`
+	ShortRad        string `json:"shortRad"`
+	TempC           string `json:"tempC"`
+	TempF           string `json:"tempF"`
+	Time            string `json:"time"`
+	UvIndex         string `json:"uvIndex"`
+	Visibility      string `json:"visibility"`
+	VisibilityMiles string `json:"visibilityMiles"`
+	WeatherCode     string `json:"weatherCode"`
+	WeatherDesc     []struct {
+		Value string `json:"value"`
+	} `json:"weatherDesc"`
+	WeatherIconURL []struct {
+		Value string `json:"value"`
+	} `json:"weatherIconUrl"`
+	Winddir16Point string `json:"winddir16Point"`
+	WinddirDegree  string `json:"winddirDegree"`
+	WindspeedKmph  string `json:"windspeedKmph"`
+	WindspeedMiles string `json:"windspeedMiles"`
 }
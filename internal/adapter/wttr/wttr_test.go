@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
 	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/wttr"
 )
 
@@ -37,7 +39,7 @@ func (s *WttrSuite) TestFailedRequest() {
 	})
 
 	tg := wttr.NewTemperatureGetter(s.newGetter(rt))
-	temp, err := tg.GetTemperature(context.Background(), "São Paulo")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
 
 	s.ErrorIs(err, expectedErr)
 	s.Zero(temp)
@@ -52,7 +54,7 @@ func (s *WttrSuite) TestNonOKStatusCode() {
 	})
 
 	tg := wttr.NewTemperatureGetter(s.newGetter(rt))
-	temp, err := tg.GetTemperature(context.Background(), "São Paulo")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
 
 	s.ErrorAs(err, &wttr.ErrStatusCode{})
 	s.Zero(temp)
@@ -67,7 +69,7 @@ func (s *WttrSuite) TestInvalidJSON() {
 	})
 
 	tg := wttr.NewTemperatureGetter(s.newGetter(rt))
-	temp, err := tg.GetTemperature(context.Background(), "São Paulo")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
 
 	s.Error(err)
 	s.Zero(temp)
@@ -82,7 +84,7 @@ func (s *WttrSuite) TestNoConditionFound() {
 	})
 
 	tg := wttr.NewTemperatureGetter(s.newGetter(rt))
-	temp, err := tg.GetTemperature(context.Background(), "São Paulo")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
 
 	s.ErrorIs(err, wttr.ErrNoConditionFound)
 	s.Zero(temp)
@@ -98,8 +100,145 @@ func (s *WttrSuite) TestSuccessfulTemperature() {
 	})
 
 	tg := wttr.NewTemperatureGetter(s.newGetter(rt))
-	temp, err := tg.GetTemperature(context.Background(), "São Paulo")
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "São Paulo"})
 
 	s.NoError(err)
 	s.Equal(25.0, temp)
 }
+
+func (s *WttrSuite) TestSuccessfulObservation() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"current_condition":[{"temp_C":"25","FeelsLikeC":"27",` +
+			`"humidity":"60","pressure":"1015","windspeedKmph":"11",` +
+			`"winddirDegree":"120","winddir16Point":"ESE","uvIndex":"6",` +
+			`"visibility":"10","cloudcover":"25","precipMM":"0.1",` +
+			`"lang_pt":[{"value":"Parcialmente nublado"}],` +
+			`"weatherDesc":[{"value":"Partly cloudy"}],` +
+			`"localObsDateTime":"2025-01-15 11:30 PM","observation_time":"02:30 AM"}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	og := wttr.NewTemperatureGetter(s.newGetter(rt)).(domain.ObservationGetter)
+	obs, err := og.GetObservation(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.NoError(err)
+	s.Equal(domain.Observation{
+		TempC:         25,
+		FeelsLikeC:    27,
+		Humidity:      60,
+		PressureHPa:   1015,
+		WindSpeedKmph: 11,
+		WindDirDegree: 120,
+		WindDir:       "ESE",
+		UVIndex:       6,
+		VisibilityKm:  10,
+		CloudCover:    25,
+		PrecipMM:      0.1,
+		Description:   "Parcialmente nublado",
+		ObservedAt:    time.Date(2025, 1, 16, 2, 30, 0, 0, time.UTC),
+		Source:        "wttr",
+	}, obs)
+}
+
+func (s *WttrSuite) TestNoForecastFound() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"weather":[]}`)),
+		}, nil
+	})
+
+	fg := wttr.NewForecastGetter(s.newGetter(rt))
+	forecast, err := fg.GetForecast(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.ErrorIs(err, wttr.ErrNoForecastFound)
+	s.Empty(forecast)
+}
+
+func (s *WttrSuite) TestSuccessfulForecast() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"weather":[{"date":"2025-01-15","mintempC":"19","maxtempC":"29",` +
+			`"avgtempC":"24","hourly":[{"time":"0","tempC":"20","FeelsLikeC":"20",` +
+			`"humidity":"80","chanceofrain":"10","precipMM":"0.0",` +
+			`"lang_pt":[{"value":"Céu limpo"}]},{"time":"1500","tempC":"29",` +
+			`"FeelsLikeC":"31","humidity":"50","chanceofrain":"70",` +
+			`"precipMM":"1.2","weatherDesc":[{"value":"Patchy rain"}]}]}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	fg := wttr.NewForecastGetter(s.newGetter(rt))
+	forecast, err := fg.GetForecast(context.Background(), domain.Location{City: "São Paulo"})
+
+	s.NoError(err)
+	s.Equal(domain.Forecast{
+		Days: []domain.ForecastDay{{
+			Date: "2025-01-15",
+			MinC: 19,
+			MaxC: 29,
+			AvgC: 24,
+			Hourly: []domain.ForecastHour{{
+				Time:         "00:00",
+				TempC:        20,
+				FeelsLikeC:   20,
+				Humidity:     80,
+				ChanceOfRain: 10,
+				Description:  "Céu limpo",
+			}, {
+				Time:         "15:00",
+				TempC:        29,
+				FeelsLikeC:   31,
+				Humidity:     50,
+				ChanceOfRain: 70,
+				PrecipMM:     1.2,
+				Description:  "Patchy rain",
+			}},
+		}},
+		Source: "wttr",
+	}, forecast)
+}
+
+func (s *WttrSuite) TestCoordinatesInURL() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		s.Equal("/-9.07,-44.36", r.URL.Path)
+
+		body := `{"current_condition":[{"temp_C":"30"}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	tg := wttr.NewTemperatureGetter(s.newGetter(rt))
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{
+		City:        "Bom Jesus",
+		UF:          "PI",
+		Coordinates: &domain.Coordinates{Latitude: -9.07, Longitude: -44.36},
+	})
+
+	s.NoError(err)
+	s.Equal(30.0, temp)
+}
+
+func (s *WttrSuite) TestNameInURLIncludesUF() {
+	rt := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		s.Equal("/Bom Jesus, RS", r.URL.Path)
+
+		body := `{"current_condition":[{"temp_C":"18"}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+
+	tg := wttr.NewTemperatureGetter(s.newGetter(rt))
+	temp, err := tg.GetTemperature(context.Background(), domain.Location{City: "Bom Jesus", UF: "RS"})
+
+	s.NoError(err)
+	s.Equal(18.0, temp)
+}
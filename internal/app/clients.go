@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/bulkhead"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/ratelimit"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/retry"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/tlstrust"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+)
+
+// providerClients hands out the HTTP client of every upstream provider. The
+// requests of a provider are retried and, when it has a rate limit, kept
+// under its limits. The limits are enforced below the retries, so that
+// retried requests count too, and shared by every adapter of a provider.
+// Every call, retries included, is bounded by the timeout of the provider.
+//
+// Each provider has its own copy of the base transport, and so its own
+// connection pool, behind its bulkhead, so that a slow provider can not
+// starve the others. Its certificates are verified against the trusted CAs
+// and the pins of the provider.
+//
+// The clients are kept in a pool, so that a provider whose settings and CAs
+// did not change keeps its client, and with it its connections, across
+// reloads. The CAs are known by caDigest, the digest of the contents of the
+// CA files, so that a CA file rewritten in place takes effect too. The rate
+// limiter of a provider has a pool entry of its own, so that its quota is
+// only reset when its limits change.
+type providerClients struct {
+	transport *http.Transport
+	caDigest  string
+	upstream  config.Upstream
+	pool      *pool
+}
+
+func newProviderClients(transport *http.Transport, caDigest string, upstream config.Upstream, pool *pool) *providerClients {
+	return &providerClients{transport: transport, caDigest: caDigest, upstream: upstream, pool: pool}
+}
+
+func (p *providerClients) get(name string) *http.Client {
+	provider := p.upstream.Provider(name)
+
+	key := fmt.Sprintf("client %s %v %+v %+v %s %q", name, provider.Timeout, provider.Bulkhead, p.upstream.Retry,
+		p.caDigest, provider.Pins)
+
+	var limiter *ratelimit.Limiter
+	if rl := provider.RateLimit; rl != nil {
+		limiter = p.limiter(name, *rl)
+		key += fmt.Sprintf(" %+v", *rl)
+	}
+
+	cl, _ := pooledGet(p.pool, key, func() (*http.Client, func() error, error) {
+		cl, transport := p.open(name, provider, limiter)
+		return cl, func() error {
+			transport.CloseIdleConnections()
+			return nil
+		}, nil
+	})
+
+	return cl
+}
+
+// limiter returns the rate limiter of the provider name, which is shared by
+// its clients for as long as its limits do not change.
+func (p *providerClients) limiter(name string, rl config.RateLimit) *ratelimit.Limiter {
+	limiter, _ := pooledGet(p.pool, fmt.Sprintf("ratelimit %s %+v", name, rl),
+		func() (*ratelimit.Limiter, func() error, error) {
+			mode := ratelimit.Wait
+			if rl.Mode == "reject" {
+				mode = ratelimit.Reject
+			}
+			return ratelimit.NewLimiter(name, ratelimit.Limits{
+				Rate:       rl.Rate,
+				Burst:      rl.Burst,
+				Mode:       mode,
+				MaxWait:    rl.MaxWait,
+				DailyQuota: rl.DailyQuota,
+			}), nil, nil
+		})
+
+	return limiter
+}
+
+func (p *providerClients) open(name string, provider config.Provider, limiter *ratelimit.Limiter) (*http.Client, *http.Transport) {
+	transport := p.transport.Clone()
+	transport.MaxConnsPerHost = provider.Bulkhead.MaxConcurrent
+	transport.MaxIdleConnsPerHost = provider.Bulkhead.MaxConcurrent
+	transport.TLSClientConfig = tlstrust.WithPins(transport.TLSClientConfig, provider.Pins)
+
+	var rt http.RoundTripper = bulkhead.NewTransport(tlstrust.NewTransport(transport, name), name, bulkhead.Settings{
+		MaxConcurrent: provider.Bulkhead.MaxConcurrent,
+		QueueTimeout:  provider.Bulkhead.QueueTimeout,
+	})
+
+	if limiter != nil {
+		rt = limiter.Transport(rt)
+	}
+
+	cl := &http.Client{
+		Transport: retry.NewTransport(rt, retryPolicy(p.upstream.Retry)),
+		Timeout:   provider.Timeout,
+	}
+
+	return cl, transport
+}
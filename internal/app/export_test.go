@@ -0,0 +1,44 @@
+package app
+
+import (
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/reload"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	Pool     = pool
+	ServiceB = serviceB
+)
+
+var (
+	NewPool         = newPool
+	NewBreaker      = newBreaker
+	NewHedger       = newHedger
+	NewServiceBOnly = newServiceB
+	RestartRequired = restartRequired
+	WatchFile       = watchFile
+)
+
+func PooledGet[T any](p *Pool, key string, open func() (T, func() error, error)) (T, error) {
+	return pooledGet(p, key, open)
+}
+
+func (p *pool) Begin()                     { p.begin() }
+func (p *pool) Commit(grace time.Duration) { p.commit(grace) }
+func (p *pool) Abort() error               { return p.abort() }
+func (p *pool) Close() error               { return p.close() }
+
+func (b *serviceB) Reload(cfg *config.Config) error { return b.reload(cfg) }
+func (b *serviceB) Getters() reload.Getters         { return b.chains.Load() }
+func (b *serviceB) Close() error                    { return b.pool.close() }
+
+func NewServiceTracers(names []string, providers ...trace.TracerProvider) trace.TracerProvider {
+	t := &serviceTracers{providers: map[string]trace.TracerProvider{}}
+	for i, name := range names {
+		t.add(name, providers[i])
+	}
+	return t
+}
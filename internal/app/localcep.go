@@ -0,0 +1,43 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/localcep"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+)
+
+// ImportCEPs implements the import-ceps command, which builds or refreshes
+// the index read by the "local" CEP provider from a CSV in the ViaCEP
+// layout. The index defaults to the one configured for the provider.
+func ImportCEPs(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import-ceps", flag.ExitOnError)
+	db := fs.String("db", cfg.CEP.LocalDB, "index to build or replace")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: import-ceps [-db path] table.csv")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT)
+	defer stop()
+
+	n, err := localcep.ImportFile(ctx, *db, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("imported %d records into %s", n, *db)
+	return nil
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// pool keeps the resources of the chains of service B, such as files,
+// connections and in-memory caches, so that a chain rebuilt on reload reuses
+// those it still needs rather than opening them again. A resource is known
+// by a key made of the settings it was opened with.
+//
+// A chain is built between begin and either commit, which retires the
+// resources the new chain no longer needs, or abort, which closes those it
+// opened.
+type pool struct {
+	items    map[string]*pooled
+	building map[string]*pooled
+
+	mu sync.Mutex
+	// retired holds, by commit, the resources waiting for their grace to
+	// end
+	retired map[int][]func() error
+	commits int
+}
+
+type pooled struct {
+	value any
+	close func() error
+}
+
+func newPool() *pool {
+	return &pool{items: make(map[string]*pooled), retired: make(map[int][]func() error)}
+}
+
+// pooledGet returns the resource known by key, opening it if the pool has
+// none. A nil close function means there is nothing to release.
+func pooledGet[T any](p *pool, key string, open func() (T, func() error, error)) (T, error) {
+	if r, ok := p.building[key]; ok {
+		return r.value.(T), nil
+	}
+	if r, ok := p.items[key]; ok {
+		p.building[key] = r
+		return r.value.(T), nil
+	}
+
+	v, closeFn, err := open()
+	if err != nil {
+		return v, err
+	}
+	if closeFn == nil {
+		closeFn = func() error { return nil }
+	}
+	p.building[key] = &pooled{value: v, close: closeFn}
+
+	return v, nil
+}
+
+func (p *pool) begin() {
+	p.building = make(map[string]*pooled)
+}
+
+// commit keeps the resources of the chain just built and retires the others,
+// which are closed after grace, once the calls still using them are over.
+func (p *pool) commit(grace time.Duration) {
+	var stale []func() error
+	for key, r := range p.items {
+		if _, ok := p.building[key]; !ok {
+			stale = append(stale, r.close)
+		}
+	}
+	p.items, p.building = p.building, nil
+
+	if len(stale) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	commit := p.commits
+	p.commits++
+	p.retired[commit] = stale
+	p.mu.Unlock()
+
+	time.AfterFunc(grace, func() { _ = p.closeRetired(commit) })
+}
+
+// abort closes the resources opened for the chain that failed to build.
+func (p *pool) abort() error {
+	var errs []error
+	for key, r := range p.building {
+		if _, ok := p.items[key]; !ok {
+			errs = append(errs, r.close())
+		}
+	}
+	p.building = nil
+
+	return errors.Join(errs...)
+}
+
+// closeRetired closes the resources retired by the given commits, or by
+// every commit when none is given.
+func (p *pool) closeRetired(commits ...int) error {
+	p.mu.Lock()
+	if len(commits) == 0 {
+		for commit := range p.retired {
+			commits = append(commits, commit)
+		}
+	}
+	var retired []func() error
+	for _, commit := range commits {
+		retired = append(retired, p.retired[commit]...)
+		delete(p.retired, commit)
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, c := range retired {
+		errs = append(errs, c())
+	}
+
+	return errors.Join(errs...)
+}
+
+// close releases every resource of the pool.
+func (p *pool) close() error {
+	errs := []error{p.closeRetired()}
+	for _, r := range p.items {
+		errs = append(errs, r.close())
+	}
+	p.items = nil
+
+	return errors.Join(errs...)
+}
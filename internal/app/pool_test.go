@@ -0,0 +1,138 @@
+package app_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/app"
+)
+
+// resource counts how many times it was opened and closed.
+type resource struct {
+	name   string
+	opened int
+	closed chan struct{}
+}
+
+func newResource(name string) *resource {
+	return &resource{name: name, closed: make(chan struct{}, 10)}
+}
+
+func (r *resource) open() (*resource, func() error, error) {
+	r.opened++
+	return r, func() error {
+		r.closed <- struct{}{}
+		return nil
+	}, nil
+}
+
+func (r *resource) isClosed() bool {
+	return len(r.closed) > 0
+}
+
+type PoolSuite struct {
+	suite.Suite
+	pool *app.Pool
+}
+
+func TestPoolSuite(t *testing.T) {
+	suite.Run(t, new(PoolSuite))
+}
+
+func (s *PoolSuite) SetupTest() {
+	s.pool = app.NewPool()
+}
+
+func (s *PoolSuite) TearDownTest() {
+	s.NoError(s.pool.Close())
+}
+
+// build gets the resources as a chain built from them would.
+func (s *PoolSuite) build(grace time.Duration, resources ...*resource) {
+	s.pool.Begin()
+	for _, r := range resources {
+		got, err := app.PooledGet(s.pool, r.name, r.open)
+		s.Require().NoError(err)
+		s.Require().Same(r, got)
+	}
+	s.pool.Commit(grace)
+}
+
+func (s *PoolSuite) TestUnchangedKeyIsReused() {
+	a := newResource("a")
+
+	s.build(time.Hour, a)
+	s.build(time.Hour, a)
+
+	s.Equal(1, a.opened)
+	s.False(a.isClosed())
+}
+
+func (s *PoolSuite) TestRetiredClosedAfterGrace() {
+	a, b := newResource("a"), newResource("b")
+
+	s.build(time.Hour, a)
+	s.build(50*time.Millisecond, b)
+
+	s.False(a.isClosed())
+	s.Eventually(a.isClosed, time.Second, 10*time.Millisecond)
+	s.False(b.isClosed())
+}
+
+func (s *PoolSuite) TestEachCommitHasItsOwnGrace() {
+	a, b, c := newResource("a"), newResource("b"), newResource("c")
+
+	s.build(time.Hour, a)
+	s.build(20*time.Millisecond, b)
+	s.build(time.Hour, c)
+
+	s.Eventually(a.isClosed, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	s.False(b.isClosed())
+}
+
+func (s *PoolSuite) TestAbortClosesOnlyWhatTheBuildOpened() {
+	a, b := newResource("a"), newResource("b")
+
+	s.build(time.Hour, a)
+
+	s.pool.Begin()
+	_, err := app.PooledGet(s.pool, "a", a.open)
+	s.Require().NoError(err)
+	_, err = app.PooledGet(s.pool, "b", b.open)
+	s.Require().NoError(err)
+	s.NoError(s.pool.Abort())
+
+	s.True(b.isClosed())
+	s.False(a.isClosed())
+
+	s.build(time.Hour, a)
+	s.Equal(1, a.opened)
+}
+
+func (s *PoolSuite) TestFailedOpenIsNotKept() {
+	s.pool.Begin()
+	_, err := app.PooledGet(s.pool, "a", func() (*resource, func() error, error) {
+		return nil, nil, errors.New("boom")
+	})
+	s.Error(err)
+	s.NoError(s.pool.Abort())
+
+	a := newResource("a")
+	s.build(time.Hour, a)
+	s.Equal(1, a.opened)
+}
+
+func (s *PoolSuite) TestCloseClosesRetiredToo() {
+	a, b := newResource("a"), newResource("b")
+
+	s.build(time.Hour, a)
+	s.build(time.Hour, b)
+
+	s.NoError(s.pool.Close())
+
+	s.True(a.isClosed())
+	s.True(b.isClosed())
+}
@@ -0,0 +1,313 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/domain"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/brasilapi"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/breaker"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/cache"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/fallback"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/hedge"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/ibge"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/localcep"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/opencep"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/openmeteo"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/openweathermap"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/postmon"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/retry"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/viacep"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/weatherapi"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/wttr"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+)
+
+// newAddressGetter builds the chain of CEP providers. Every remote provider
+// gets its own circuit breaker.
+func newAddressGetter(cfg *config.Config, clients *providerClients) (domain.AddressGetter, error) {
+	var providers []fallback.AddressProvider
+	for _, name := range cfg.CEP.Providers {
+		ag, err := newAddressProvider(cfg, clients, name)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, fallback.AddressProvider{Name: name, Getter: ag})
+	}
+
+	return fallback.NewAddressGetter(cfg.CEP.NotFoundAuthoritative, providers...)
+}
+
+func newAddressProvider(cfg *config.Config, clients *providerClients, name string) (domain.AddressGetter, error) {
+	if name == "local" {
+		// an index replaced by import-ceps is a new file at the same path,
+		// which is opened on the next reload
+		key := "localcep " + cfg.CEP.LocalDB
+		if info, err := os.Stat(cfg.CEP.LocalDB); err == nil {
+			key += fmt.Sprintf(" %d %d", info.ModTime().UnixNano(), info.Size())
+		}
+		return pooledGet(clients.pool, key, func() (domain.AddressGetter, func() error, error) {
+			l, err := localcep.Open(cfg.CEP.LocalDB)
+			if err != nil {
+				return nil, nil, err
+			}
+			return l, l.Close, nil
+		})
+	}
+
+	constructors := map[string]func(*http.Client) domain.AddressGetter{
+		"viacep":    viacep.NewAddressGetter,
+		"brasilapi": brasilapi.NewAddressGetter,
+		"opencep":   opencep.NewAddressGetter,
+		"postmon":   postmon.NewAddressGetter,
+	}
+
+	newGetter, ok := constructors[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown CEP provider %q", name)
+	}
+
+	ag := newGetter(clients.get(name))
+	if cfg.Upstream.Breaker.Enabled {
+		ag = newBreaker(clients.pool, name, cfg.Upstream.Breaker).AddressGetter(ag)
+	}
+
+	return ag, nil
+}
+
+// newBreaker returns the circuit breaker of the provider name, which keeps
+// its state across reloads for as long as its settings do not change.
+func newBreaker(p *pool, name string, cfg config.Breaker) *breaker.Breaker {
+	settings := breakerSettings(cfg)
+	b, _ := pooledGet(p, fmt.Sprintf("breaker %s %+v", name, settings), func() (*breaker.Breaker, func() error, error) {
+		return breaker.New(name, settings), nil, nil
+	})
+
+	return b
+}
+
+// newHedger returns the hedger named name, which keeps the latencies it
+// observed across reloads for as long as its settings do not change.
+func newHedger(p *pool, name string, settings hedge.Settings) *hedge.Hedger {
+	h, _ := pooledGet(p, fmt.Sprintf("hedger %s %+v", name, settings), func() (*hedge.Hedger, func() error, error) {
+		return hedge.New(name, settings), nil, nil
+	})
+
+	return h
+}
+
+// withHedging hedges the calls to ag and tg, unless hedging is disabled.
+func withHedging(cfg *config.Config, clients *providerClients, ag domain.AddressGetter, tg domain.TemperatureGetter) (domain.AddressGetter, domain.TemperatureGetter, error) {
+	h := cfg.Hedge
+	if h.Delay <= 0 && h.Percentile <= 0 {
+		return ag, tg, nil
+	}
+
+	settings := hedge.Settings{Delay: h.Delay, Percentile: h.Percentile, MinSamples: h.MinSamples}
+	if settings.Delay <= 0 {
+		settings.Delay = time.Second
+	}
+
+	var (
+		altAG domain.AddressGetter
+		altTG domain.TemperatureGetter
+		err   error
+	)
+	if h.CEPProvider != "" {
+		altAG, err = newAddressProvider(cfg, clients, h.CEPProvider)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if h.TemperatureProvider != "" {
+		altTG, err = newTemperatureProvider(cfg, clients, h.TemperatureProvider)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return newHedger(clients.pool, "cep", settings).AddressGetter(ag, altAG),
+		newHedger(clients.pool, "temperature", settings).TemperatureGetter(tg, altTG),
+		nil
+}
+
+func breakerSettings(b config.Breaker) breaker.Settings {
+	return breaker.Settings{
+		FailureRatio: b.FailureRatio,
+		MinRequests:  b.MinRequests,
+		Window:       b.Window,
+		Cooldown:     b.Cooldown,
+	}
+}
+
+func retryPolicy(r config.Retry) retry.Policy {
+	return retry.Policy{MaxAttempts: r.MaxAttempts, BaseDelay: r.BaseDelay, MaxDelay: r.MaxDelay}
+}
+
+// newTemperatureGetter builds the chain of temperature providers, each one
+// guarded by a circuit breaker. A provider whose quota is exhausted is
+// skipped.
+func newTemperatureGetter(cfg *config.Config, clients *providerClients) (domain.TemperatureGetter, error) {
+	var providers []fallback.TemperatureProvider
+	for _, name := range cfg.Temperature.Providers {
+		tg, err := newTemperatureProvider(cfg, clients, name)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, fallback.TemperatureProvider{Name: name, Getter: tg})
+	}
+
+	if len(providers) == 1 {
+		return providers[0].Getter, nil
+	}
+
+	return fallback.NewTemperatureGetter(providers...)
+}
+
+func newTemperatureProvider(cfg *config.Config, clients *providerClients, name string) (domain.TemperatureGetter, error) {
+	tg, err := newTemperatureAdapter(cfg.Temperature, clients.get(name), name)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Upstream.Breaker.Enabled {
+		tg = newBreaker(clients.pool, name, cfg.Upstream.Breaker).TemperatureGetter(tg)
+	}
+
+	return tg, nil
+}
+
+func newTemperatureAdapter(cfg config.Temperature, cl *http.Client, name string) (domain.TemperatureGetter, error) {
+	switch name {
+	case "wttr":
+		return wttr.NewTemperatureGetter(cl), nil
+	case "openmeteo":
+		return openmeteo.NewTemperatureGetter(cl), nil
+	case "weatherapi":
+		if cfg.WeatherAPIKey == "" {
+			return nil, errors.New("a key is required by the weatherapi provider")
+		}
+		return weatherapi.NewTemperatureGetter(cl, cfg.WeatherAPIKey), nil
+	case "openweathermap":
+		if cfg.OpenWeatherMapKey == "" {
+			return nil, errors.New("a key is required by the openweathermap provider")
+		}
+		return openweathermap.NewTemperatureGetter(cl, cfg.OpenWeatherMapKey), nil
+	default:
+		return nil, fmt.Errorf("unknown temperature provider %q", name)
+	}
+}
+
+// newGeocoder loads the IBGE municipality table from the configured file,
+// falling back to the embedded one. It returns nil when geocoding is
+// disabled, so the weather is looked up by city name.
+func newGeocoder(cfg config.ServiceB) (domain.Geocoder, error) {
+	if !cfg.Geocoding {
+		return nil, nil
+	}
+
+	if cfg.IBGEMunicipalitiesFile != "" {
+		return ibge.NewGeocoderFromFile(cfg.IBGEMunicipalitiesFile)
+	}
+
+	return ibge.NewGeocoder()
+}
+
+// withCache wraps ag and tg with caches, in memory or in Redis, shared by
+// every replica. Addresses missing from those may also be kept in a file.
+// The files, connections and in-memory caches are taken from p, so that the
+// cached entries outlive reloads that keep their settings.
+func withCache(cfg config.Cache, p *pool, ag domain.AddressGetter, tg domain.TemperatureGetter) (domain.AddressGetter, domain.TemperatureGetter, error) {
+	addr, temp := cfg.Address, cfg.Temperature
+
+	// the disk cache can only be opened once, so a new compaction interval
+	// is left for a restart
+	var disk *cache.Bolt
+	if addr.DiskPath != "" {
+		var err error
+		disk, err = pooledGet(p, "bolt "+addr.DiskPath, func() (*cache.Bolt, func() error, error) {
+			disk, err := cache.OpenBolt(addr.DiskPath, cache.BoltOptions{CompactInterval: addr.DiskCompactInterval})
+			if err != nil {
+				return nil, nil, err
+			}
+			return disk, disk.Close, nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		// lookups are counted by the first cache they reach, which is
+		// the one in front of the disk, if any
+		var store cache.Store = disk
+		if addr.TTL <= 0 {
+			store = disk.CountHits(disk)
+		}
+		ag = cache.NewAddressGetter(ag, store, addr.DiskTTL, addr.NotFoundTTL)
+	}
+
+	var (
+		addrStore, tempStore cache.Store
+		storeKey             string
+	)
+
+	switch cfg.Backend {
+	case "memory":
+		storeKey = fmt.Sprintf("lru address %d", addr.Size)
+		addrStore, _ = pooledGet(p, storeKey, func() (cache.Store, func() error, error) {
+			return cache.NewLRU(addr.Size), nil, nil
+		})
+		tempStore, _ = pooledGet(p, fmt.Sprintf("lru temperature %d", temp.Size), func() (cache.Store, func() error, error) {
+			return cache.NewLRU(temp.Size), nil, nil
+		})
+	case "redis":
+		storeKey = "redis " + cfg.RedisURL
+		client, err := pooledGet(p, storeKey, func() (*redis.Client, func() error, error) {
+			opts, err := redis.ParseURL(cfg.RedisURL)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing the Redis URL: %w", err)
+			}
+			client := redis.NewClient(opts)
+			return client, client.Close, nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		addrStore = cache.NewRedis(client, "weather", cfg.KeyVersion)
+		tempStore = addrStore
+	default:
+		return nil, nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+
+	if addr.TTL > 0 {
+		// the store is warmed up once, when it is first used with the disk
+		if disk != nil && addr.WarmStart > 0 {
+			_, _ = pooledGet(p, "warm start "+addr.DiskPath+" "+storeKey, func() (struct{}, func() error, error) {
+				n, err := disk.Preload(context.Background(), addrStore, min(addr.WarmStart, addr.Size), addr.TTL)
+				if err != nil {
+					log.Println("address cache warm start error:", err)
+				}
+				log.Printf("loaded %d addresses from the disk cache", n)
+				return struct{}{}, nil, nil
+			})
+		}
+		store := addrStore
+		if disk != nil {
+			store = disk.CountHits(addrStore)
+		}
+		ag = cache.NewAddressGetter(ag, store, addr.TTL, addr.NotFoundTTL)
+	}
+	if temp.TTL > 0 {
+		tg = cache.NewTemperatureGetter(tg, tempStore, cache.Freshness{
+			TTL:                  temp.TTL,
+			StaleWhileRevalidate: temp.StaleWhileRevalidate,
+			StaleIfError:         temp.StaleIfError,
+		})
+	}
+
+	return ag, tg, nil
+}
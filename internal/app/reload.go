@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// reloadConfig loads the configuration of the command name again and hands
+// it to the services that can be reconfigured. The outcome is logged and
+// traced, as the first of those services, along with the changes from
+// initial, the configuration the process started with, that only take effect
+// on restart.
+func reloadConfig(ctx context.Context, initial *config.Config, name string, args []string, services []*Service) {
+	tracer := services[0].name
+	for _, s := range services {
+		if s.reload != nil {
+			tracer = s.name
+			break
+		}
+	}
+
+	_, span := otel.Tracer(tracer).Start(ctx, "reload-config")
+	defer span.End()
+
+	cfg, _, err := config.Load(name, args, os.Environ(), io.Discard)
+	if err == nil {
+		for _, s := range services {
+			if s.reload == nil {
+				continue
+			}
+			if rerr := s.reload(cfg); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("%s: %w", s.name, rerr))
+			}
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "configuration rejected")
+		log.Println("configuration rejected, keeping the current one:", err)
+		return
+	}
+
+	keys := restartRequired(initial, cfg)
+	span.SetAttributes(attribute.StringSlice("config.restart_required", keys))
+	if len(keys) > 0 {
+		log.Printf("configuration reloaded; changes to %s take effect on restart", strings.Join(keys, ", "))
+		return
+	}
+
+	log.Println("configuration reloaded")
+}
+
+// restartRequired returns the keys of the settings that differ between old
+// and cfg but are not reloaded.
+func restartRequired(old, cfg *config.Config) []string {
+	settings := []struct {
+		key      string
+		old, cfg any
+	}{
+		{"service_a", old.ServiceA, cfg.ServiceA},
+		{"service_b.addr", old.ServiceB.Addr, cfg.ServiceB.Addr},
+		{"service_b.geocoding", old.ServiceB.Geocoding, cfg.ServiceB.Geocoding},
+		{"service_b.ibge_municipalities_file", old.ServiceB.IBGEMunicipalitiesFile, cfg.ServiceB.IBGEMunicipalitiesFile},
+		{"service_b.tls", tlsSettings(old.ServiceB), tlsSettings(cfg.ServiceB)},
+		{"server", old.Server, cfg.Server},
+		{"telemetry", old.Telemetry, cfg.Telemetry},
+		{"request.timeout", old.Request.Timeout, cfg.Request.Timeout},
+		{"reload", old.Reload, cfg.Reload},
+	}
+
+	var keys []string
+	for _, s := range settings {
+		if !reflect.DeepEqual(s.old, s.cfg) {
+			keys = append(keys, s.key)
+		}
+	}
+
+	return keys
+}
+
+func tlsSettings(b config.ServiceB) []any {
+	return []any{b.TLSCertFile, b.TLSKeyFile, b.ClientCAFiles, b.AllowedClients}
+}
+
+// watchFile checks the file at path every interval and reports its changes
+// on the returned channel. It reports nothing unless both are set.
+func watchFile(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	if path == "" || interval <= 0 {
+		return changes
+	}
+
+	last, _ := os.Stat(path)
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			// a file missing while it is replaced is checked again later
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			last = info
+
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changes
+}
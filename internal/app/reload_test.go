@@ -0,0 +1,157 @@
+package app_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/hedge"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/localcep"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/app"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+)
+
+type ReloadSuite struct {
+	suite.Suite
+}
+
+func TestReloadSuite(t *testing.T) {
+	suite.Run(t, new(ReloadSuite))
+}
+
+func (s *ReloadSuite) TestRejectedConfigKeepsChains() {
+	b, err := app.NewServiceBOnly(config.Default())
+	s.Require().NoError(err)
+	defer b.Close()
+
+	old := b.Getters()
+
+	cfg := config.Default()
+	cfg.Upstream.CAFiles = []string{filepath.Join(s.T().TempDir(), "missing.pem")}
+	s.Error(b.Reload(cfg))
+
+	s.Same(old.AddressGetter, b.Getters().AddressGetter)
+	s.Same(old.TemperatureGetter, b.Getters().TemperatureGetter)
+	s.Same(old.ForecastGetter, b.Getters().ForecastGetter)
+
+	cfg = config.Default()
+	cfg.Upstream.Timeout = time.Second
+	s.Require().NoError(b.Reload(cfg))
+
+	s.NotSame(old.AddressGetter, b.Getters().AddressGetter)
+}
+
+func (s *ReloadSuite) TestReloadKeepsBreakersAndHedgers() {
+	p := app.NewPool()
+	defer p.Close()
+
+	cfg := config.Default().Upstream.Breaker
+	settings := hedge.Settings{Delay: time.Second, MinSamples: 20}
+
+	p.Begin()
+	b := app.NewBreaker(p, "viacep", cfg)
+	h := app.NewHedger(p, "cep", settings)
+	p.Commit(0)
+
+	p.Begin()
+	s.Same(b, app.NewBreaker(p, "viacep", cfg))
+	s.NotSame(b, app.NewBreaker(p, "brasilapi", cfg))
+	s.Same(h, app.NewHedger(p, "cep", settings))
+	s.NotSame(h, app.NewHedger(p, "temperature", settings))
+	p.Commit(0)
+
+	cfg.Cooldown *= 2
+	settings.Percentile = 0.99
+
+	p.Begin()
+	s.NotSame(b, app.NewBreaker(p, "viacep", cfg))
+	s.NotSame(h, app.NewHedger(p, "cep", settings))
+	p.Commit(0)
+}
+
+func (s *ReloadSuite) TestReloadReopensReplacedCEPIndex() {
+	const header = "cep,logradouro,complemento,bairro,localidade,uf\n"
+
+	path := filepath.Join(s.T().TempDir(), "ceps.db")
+	_, err := localcep.Import(context.Background(), path, strings.NewReader(header+"01001-000,Praça da Sé,,Sé,São Paulo,SP\n"))
+	s.Require().NoError(err)
+
+	cfg := config.Default()
+	cfg.CEP.Providers = []string{"local"}
+	cfg.CEP.LocalDB = path
+	cfg.Cache.Address.TTL = 0
+
+	b, err := app.NewServiceBOnly(cfg)
+	s.Require().NoError(err)
+	defer b.Close()
+
+	addr, err := b.Getters().AddressGetter.GetAddress(context.Background(), "01001000")
+	s.Require().NoError(err)
+	s.Equal("Praça da Sé", addr.Street)
+
+	_, err = localcep.Import(context.Background(), path, strings.NewReader(header+"01001-000,Praça da Sé Nova,,Sé,São Paulo,SP\n"))
+	s.Require().NoError(err)
+	s.Require().NoError(b.Reload(cfg))
+
+	addr, err = b.Getters().AddressGetter.GetAddress(context.Background(), "01001000")
+	s.Require().NoError(err)
+	s.Equal("Praça da Sé Nova", addr.Street)
+}
+
+func (s *ReloadSuite) TestRestartRequired() {
+	old := config.Default()
+
+	cfg := config.Default()
+	cfg.Upstream.Timeout = time.Second
+	cfg.Cache.Address.TTL = time.Hour
+	s.Empty(app.RestartRequired(old, cfg))
+
+	cfg.ServiceB.Addr = ":9090"
+	cfg.ServiceB.TLSCertFile = "cert.pem"
+	cfg.Request.Timeout = time.Second
+	s.Equal([]string{"service_b.addr", "service_b.tls", "request.timeout"}, app.RestartRequired(old, cfg))
+}
+
+func (s *ReloadSuite) TestWatchFileReportsChanges() {
+	path := filepath.Join(s.T().TempDir(), "config.yaml")
+	s.Require().NoError(os.WriteFile(path, []byte("cache: {}\n"), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := app.WatchFile(ctx, path, 10*time.Millisecond)
+
+	s.Never(func() bool { return len(changes) > 0 }, 50*time.Millisecond, 10*time.Millisecond)
+
+	s.Require().NoError(os.WriteFile(path, []byte("cache: {backend: redis}\n"), 0o600))
+
+	s.Eventually(func() bool { return len(changes) > 0 }, time.Second, 10*time.Millisecond)
+}
+
+func (s *ReloadSuite) TestWatchFileSurvivesMissingFile() {
+	path := filepath.Join(s.T().TempDir(), "config.yaml")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := app.WatchFile(ctx, path, 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+
+	s.Require().NoError(os.WriteFile(path, []byte("cache: {}\n"), 0o600))
+
+	s.Eventually(func() bool { return len(changes) > 0 }, time.Second, 10*time.Millisecond)
+}
+
+func (s *ReloadSuite) TestWatchFileDisabled() {
+	path := filepath.Join(s.T().TempDir(), "config.yaml")
+	s.Require().NoError(os.WriteFile(path, nil, 0o600))
+
+	changes := app.WatchFile(context.Background(), path, 0)
+	s.Require().NoError(os.WriteFile(path, []byte("cache: {}\n"), 0o600))
+
+	s.Never(func() bool { return len(changes) > 0 }, 50*time.Millisecond, 10*time.Millisecond)
+}
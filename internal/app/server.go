@@ -0,0 +1,169 @@
+// Package app wires the adapters into services A and B, as set by the
+// configuration, and serves them.
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/mtls"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+)
+
+// Service is a service ready to be served by [Run].
+type Service struct {
+	name   string
+	server *http.Server
+	close  func() error
+	// reload, when set, reconfigures the service while it is serving
+	reload func(*config.Config) error
+}
+
+// Run serves services, each reporting telemetry under its own name, until one
+// of them fails or the process gets SIGINT or SIGTERM. Then it shuts them
+// down and releases their resources.
+//
+// On SIGHUP, and on changes to the config file when it is watched, Run loads
+// the configuration again, as the command name with args, and hands it to the
+// services that can be reconfigured. An invalid configuration is rejected,
+// keeping the current one.
+func Run(cfg *config.Config, name string, args []string, services ...*Service) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	names := make([]string, len(services))
+	for i, s := range services {
+		names[i] = s.name
+	}
+
+	shutdownTelemetry, err := initTelemetry(ctx, cfg.Telemetry, names...)
+	if err != nil {
+		for _, s := range services {
+			err = errors.Join(err, s.close())
+		}
+		return fmt.Errorf("initializing telemetry: %w", err)
+	}
+	defer func() {
+		if err := shutdownTelemetry(context.WithoutCancel(ctx)); err != nil {
+			log.Println("telemetry shutdown error:", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	for _, s := range services {
+		go func() {
+			var err error
+			if s.server.TLSConfig != nil {
+				log.Printf("%s listening on %s (HTTPS)", s.name, s.server.Addr)
+				err = s.server.ListenAndServeTLS("", "")
+			} else {
+				log.Printf("%s listening on %s", s.name, s.server.Addr)
+				err = s.server.ListenAndServe()
+			}
+			cancel(fmt.Errorf("%s: %w", s.name, err))
+		}()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	changes := watchFile(ctx, cfg.File, cfg.Reload.WatchInterval)
+
+serve:
+	for {
+		select {
+		case <-ctx.Done():
+			break serve
+		case <-hup:
+			reloadConfig(ctx, cfg, name, args, services)
+		case <-changes:
+			reloadConfig(ctx, cfg, name, args, services)
+		}
+	}
+
+	shDCtx, cnclShD := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cnclShD()
+
+	for _, s := range services {
+		if err := s.server.Shutdown(shDCtx); err != nil {
+			log.Printf("%s shutdown error: %s", s.name, err)
+		}
+		if err := s.close(); err != nil {
+			log.Printf("%s close error: %s", s.name, err)
+		}
+	}
+
+	log.Println(context.Cause(ctx))
+	return nil
+}
+
+// newServer returns a server for h on addr with the timeouts of cfg.
+func newServer(addr string, cfg config.Server, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// serverTLS returns the TLS configuration of a server presenting the key pair
+// in certFile and keyFile, or nil, to serve plain HTTP, when they are unset.
+// With clientCAFiles, clients must present a certificate of those CAs and,
+// with allowed, of one of those subjects.
+func serverTLS(certFile, keyFile string, clientCAFiles, allowed []string) (*tls.Config, error) {
+	if certFile == "" {
+		return nil, nil
+	}
+
+	cert, err := mtls.LoadCertificate(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var clientCAs *x509.CertPool
+	if len(clientCAFiles) > 0 {
+		if clientCAs, err = mtls.LoadCAs(clientCAFiles); err != nil {
+			return nil, err
+		}
+	}
+
+	return mtls.ServerConfig(cert, clientCAs, allowed), nil
+}
+
+// LoadConfig loads the configuration of the command name from args, the
+// environment and the file they name. When args ask for it, LoadConfig
+// prints the usage or the configuration and exits.
+func LoadConfig(name string, args []string) (*config.Config, error) {
+	cfg, printConfig, err := config.Load(name, args, os.Environ(), os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading configuration: %w", err)
+	}
+
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			return nil, err
+		}
+		os.Exit(0)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/clientlimit"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/deadline"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/mtls"
+	servicea "github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/service-a"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/tlstrust"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+)
+
+// NewServiceA returns service A, configured by cfg.
+func NewServiceA(cfg *config.Config) (*Service, error) {
+	limiter, err := newClientLimiter(cfg.ServiceA)
+	if err != nil {
+		return nil, fmt.Errorf("configuring client rate limits: %w", err)
+	}
+
+	transport, err := newServiceBTransport(cfg.ServiceA)
+	if err != nil {
+		return nil, fmt.Errorf("configuring TLS to service B: %w", err)
+	}
+
+	h := servicea.NewHandler(cfg.ServiceA.ServiceBURL, transport, append(limiter, deadline.Middleware(cfg.Request.Timeout, nil))...)
+
+	server := newServer(cfg.ServiceA.Addr, cfg.Server, h)
+	if server.TLSConfig, err = serverTLS(cfg.ServiceA.TLSCertFile, cfg.ServiceA.TLSKeyFile, nil, nil); err != nil {
+		return nil, fmt.Errorf("configuring TLS: %w", err)
+	}
+
+	return &Service{name: "service-a", server: server, close: func() error { return nil }}, nil
+}
+
+// newServiceBTransport returns the transport to service B, trusting the CAs
+// of the system and the configured ones, and presenting the configured
+// client certificate, if any.
+func newServiceBTransport(cfg config.ServiceA) (*http.Transport, error) {
+	roots, err := tlstrust.RootCAs(cfg.ServiceBCAFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	var cert *mtls.Certificate
+	if cfg.ClientCertFile != "" {
+		if cert, err = mtls.LoadCertificate(cfg.ClientCertFile, cfg.ClientKeyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = mtls.ClientConfig(cert, roots)
+
+	return transport, nil
+}
+
+// newClientLimiter returns the middleware limiting the requests of each
+// client of service A, unless disabled. The tiers, API keys and trusted
+// proxies are read from the JSON policy file, if set.
+func newClientLimiter(cfg config.ServiceA) ([]gin.HandlerFunc, error) {
+	if !cfg.ClientRateLimit {
+		return nil, nil
+	}
+
+	policy := clientlimit.DefaultPolicy
+	if cfg.ClientRateLimitPolicy != "" {
+		var err error
+		if policy, err = clientlimit.LoadPolicy(cfg.ClientRateLimitPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	return []gin.HandlerFunc{clientlimit.New(policy).Middleware()}, nil
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/coalesce"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/deadline"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/mtls"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/reload"
+	serviceb "github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/service-b"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/tlstrust"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/wttr"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+)
+
+// NewServiceB returns service B, configured by cfg. Its providers and
+// caches are reconfigured on reload, without restarting the server.
+func NewServiceB(cfg *config.Config) (*Service, error) {
+	b, err := newServiceB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	gc, err := newGeocoder(cfg.ServiceB)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("loading geocoder: %w", err), b.pool.close())
+	}
+
+	h := serviceb.NewHandler(b.chains.AddressGetter(), gc, b.chains.TemperatureGetter(), b.chains.ForecastGetter(),
+		b.chains.Middleware, deadline.Middleware(cfg.Request.Timeout, mtls.Authenticated))
+
+	server := newServer(cfg.ServiceB.Addr, cfg.Server, h)
+	server.TLSConfig, err = serverTLS(cfg.ServiceB.TLSCertFile, cfg.ServiceB.TLSKeyFile,
+		cfg.ServiceB.ClientCAFiles, cfg.ServiceB.AllowedClients)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("configuring TLS: %w", err), b.pool.close())
+	}
+
+	return &Service{
+		name:   "service-b",
+		server: server,
+		close:  b.pool.close,
+		reload: b.reload,
+	}, nil
+}
+
+// serviceB holds the chains of getters of service B, which are swapped for
+// new ones on reload.
+type serviceB struct {
+	pool *pool
+	// grace is how long a replaced chain may still be in use, after which
+	// the resources only it used are closed
+	grace time.Duration
+
+	// chains are swapped as a whole, and each request uses the ones current
+	// when it arrived
+	chains *reload.Chains
+}
+
+func newServiceB(cfg *config.Config) (*serviceB, error) {
+	b := &serviceB{pool: newPool(), grace: cfg.Request.Timeout}
+
+	c, err := b.build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	b.chains = reload.NewChains(c)
+
+	return b, nil
+}
+
+func (b *serviceB) reload(cfg *config.Config) error {
+	c, err := b.build(cfg)
+	if err != nil {
+		return err
+	}
+
+	b.chains.Store(c)
+
+	return nil
+}
+
+// build returns the chains of getters configured by cfg, reusing the
+// resources of the current ones.
+func (b *serviceB) build(cfg *config.Config) (reload.Getters, error) {
+	b.pool.begin()
+
+	c, err := b.newChains(cfg)
+	if err != nil {
+		return reload.Getters{}, errors.Join(err, b.pool.abort())
+	}
+
+	b.pool.commit(b.grace)
+
+	return c, nil
+}
+
+func (b *serviceB) newChains(cfg *config.Config) (reload.Getters, error) {
+	roots, caDigest, err := tlstrust.RootCAsWithDigest(cfg.Upstream.CAFiles)
+	if err != nil {
+		return reload.Getters{}, fmt.Errorf("configuring upstream TLS: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlstrust.Config(roots)
+
+	clients := newProviderClients(transport, caDigest, cfg.Upstream, b.pool)
+
+	ag, err := newAddressGetter(cfg, clients)
+	if err != nil {
+		return reload.Getters{}, fmt.Errorf("configuring CEP providers: %w", err)
+	}
+
+	tg, err := newTemperatureGetter(cfg, clients)
+	if err != nil {
+		return reload.Getters{}, fmt.Errorf("configuring temperature provider: %w", err)
+	}
+
+	fg := wttr.NewForecastGetter(clients.get("wttr"))
+
+	ag, tg, err = withHedging(cfg, clients, ag, tg)
+	if err != nil {
+		return reload.Getters{}, fmt.Errorf("configuring hedging: %w", err)
+	}
+
+	if cfg.ServiceB.CoalesceRequests {
+		ag = coalesce.NewAddressGetter(ag)
+		tg = coalesce.NewTemperatureGetter(tg)
+	}
+
+	ag, tg, err = withCache(cfg.Cache, b.pool, ag, tg)
+	if err != nil {
+		return reload.Getters{}, fmt.Errorf("configuring cache: %w", err)
+	}
+
+	ag = deadline.NewAddressGetter(ag, cfg.Request.AddressBudgetShare)
+
+	return reload.Getters{AddressGetter: ag, TemperatureGetter: tg, ForecastGetter: fg}, nil
+}
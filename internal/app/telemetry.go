@@ -0,0 +1,151 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	metricembedded "go.opentelemetry.io/otel/metric/embedded"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	traceembedded "go.opentelemetry.io/otel/trace/embedded"
+)
+
+// initTelemetry exports the traces and metrics of each service in names to
+// the OTLP collector of cfg, with the service name in its resource. The
+// standard OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES variables override
+// the resource attributes. The returned function flushes and stops the
+// exporters.
+func initTelemetry(ctx context.Context, cfg config.Telemetry, names ...string) (func(context.Context) error, error) {
+	tracers := &serviceTracers{providers: map[string]trace.TracerProvider{}}
+	meters := &serviceMeters{providers: map[string]metric.MeterProvider{}}
+
+	var shutdowns []func(context.Context) error
+	shutdown := func(ctx context.Context) error {
+		var errs []error
+		for _, fn := range shutdowns {
+			errs = append(errs, fn(ctx))
+		}
+		return errors.Join(errs...)
+	}
+
+	for _, name := range names {
+		res, err := resource.New(ctx,
+			resource.WithAttributes(
+				semconv.ServiceName(name),
+				semconv.ServiceNamespace("weather"),
+			),
+			resource.WithFromEnv(),
+			resource.WithHost(),
+		)
+		if err != nil {
+			return nil, errors.Join(err, shutdown(ctx))
+		}
+
+		tp, err := initTracer(ctx, cfg.Endpoint, res)
+		if err != nil {
+			return nil, errors.Join(err, shutdown(ctx))
+		}
+		shutdowns = append(shutdowns, tp.Shutdown)
+
+		mp, err := initMeter(ctx, cfg.Endpoint, res)
+		if err != nil {
+			return nil, errors.Join(err, shutdown(ctx))
+		}
+		shutdowns = append(shutdowns, mp.Shutdown)
+
+		tracers.add(name, tp)
+		meters.add(name, mp)
+	}
+
+	otel.SetTracerProvider(tracers)
+	otel.SetMeterProvider(meters)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return shutdown, nil
+}
+
+func initMeter(ctx context.Context, endpoint string, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	exporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpointURL(endpoint),
+		otlpmetrichttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	), nil
+}
+
+func initTracer(ctx context.Context, endpoint string, res *resource.Resource) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(endpoint),
+		otlptracehttp.WithInsecure(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	), nil
+}
+
+// serviceTracers hands out the tracers named after a service, as in
+// otel.Tracer("service-b"), from the provider of that service, so that
+// services sharing the process report as themselves. Other tracers, such as
+// those of instrumentation libraries, come from the first service's.
+type serviceTracers struct {
+	traceembedded.TracerProvider
+	first     trace.TracerProvider
+	providers map[string]trace.TracerProvider
+}
+
+func (t *serviceTracers) add(name string, p trace.TracerProvider) {
+	if t.first == nil {
+		t.first = p
+	}
+	t.providers[name] = p
+}
+
+// Tracer implements [trace.TracerProvider].
+func (t *serviceTracers) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	if p, ok := t.providers[name]; ok {
+		return p.Tracer(name, opts...)
+	}
+	return t.first.Tracer(name, opts...)
+}
+
+// serviceMeters is like [serviceTracers], for meters.
+type serviceMeters struct {
+	metricembedded.MeterProvider
+	first     metric.MeterProvider
+	providers map[string]metric.MeterProvider
+}
+
+func (m *serviceMeters) add(name string, p metric.MeterProvider) {
+	if m.first == nil {
+		m.first = p
+	}
+	m.providers[name] = p
+}
+
+// Meter implements [metric.MeterProvider].
+func (m *serviceMeters) Meter(name string, opts ...metric.MeterOption) metric.Meter {
+	if p, ok := m.providers[name]; ok {
+		return p.Meter(name, opts...)
+	}
+	return m.first.Meter(name, opts...)
+}
@@ -0,0 +1,51 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/app"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+type TelemetrySuite struct {
+	suite.Suite
+}
+
+func TestTelemetrySuite(t *testing.T) {
+	suite.Run(t, new(TelemetrySuite))
+}
+
+func (s *TelemetrySuite) provider(name string) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(sdkresource.NewSchemaless(semconv.ServiceName(name))),
+	)
+	s.T().Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	return tp, exporter
+}
+
+func (s *TelemetrySuite) TestEachServiceReportsAsItself() {
+	a, spansA := s.provider("service-a")
+	b, spansB := s.provider("service-b")
+	tracers := app.NewServiceTracers([]string{"service-a", "service-b"}, a, b)
+
+	for _, name := range []string{"service-a", "service-b", "otelhttp"} {
+		_, span := tracers.Tracer(name).Start(context.Background(), "span-of-"+name)
+		span.End()
+	}
+
+	s.Require().Len(spansB.GetSpans(), 1)
+	s.Equal("span-of-service-b", spansB.GetSpans()[0].Name)
+
+	// the tracers of instrumentation libraries report as the first service
+	s.Require().Len(spansA.GetSpans(), 2)
+	s.Equal("span-of-service-a", spansA.GetSpans()[0].Name)
+	s.Equal("span-of-otelhttp", spansA.GetSpans()[1].Name)
+}
@@ -0,0 +1,335 @@
+// Package config loads the configuration of the services from, in increasing
+// precedence, defaults, a YAML file, environment variables and command-line
+// flags.
+//
+// Every setting has a key in the file, given by the yaml tags of [Config],
+// and a flag named after that key, e.g. -cache.address.ttl. Most also have an
+// environment variable, given by the env tag. The settings of each upstream
+// provider, in [Upstream.Providers], have no flags; they are set in the file
+// or by the UPSTREAM_TIMEOUT_<PROVIDER>, UPSTREAM_RATE_LIMIT_<PROVIDER>,
+// UPSTREAM_BULKHEAD_<PROVIDER> and UPSTREAM_TLS_PINS_<PROVIDER> variables.
+package config
+
+import (
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/breaker"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/bulkhead"
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/retry"
+)
+
+// Config is the configuration of both services.
+type Config struct {
+	ServiceA    ServiceA    `yaml:"service_a"`
+	ServiceB    ServiceB    `yaml:"service_b"`
+	Server      Server      `yaml:"server"`
+	Telemetry   Telemetry   `yaml:"telemetry"`
+	Request     Request     `yaml:"request"`
+	Upstream    Upstream    `yaml:"upstream"`
+	CEP         CEP         `yaml:"cep"`
+	Temperature Temperature `yaml:"temperature"`
+	Hedge       Hedge       `yaml:"hedge"`
+	Cache       Cache       `yaml:"cache"`
+	Reload      Reload      `yaml:"reload"`
+
+	// File is the config file the configuration was loaded from, if any.
+	File string `yaml:"-"`
+}
+
+// ServiceA configures service A.
+type ServiceA struct {
+	Addr        string `yaml:"addr" env:"SERVICE_A_ADDR"`
+	ServiceBURL string `yaml:"service_b_url" env:"SERVICE_B_URL"`
+	// ClientRateLimit limits the requests of each client, by the policy in
+	// the JSON file at ClientRateLimitPolicy or the default one.
+	ClientRateLimit       bool   `yaml:"client_rate_limit" env:"CLIENT_RATE_LIMIT"`
+	ClientRateLimitPolicy string `yaml:"client_rate_limit_policy" env:"CLIENT_RATE_LIMIT_POLICY"`
+	// TLSCertFile and TLSKeyFile, when set, serve HTTPS with the key pair
+	// in those files, read again when they change.
+	TLSCertFile string `yaml:"tls_cert_file" env:"SERVICE_A_TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tls_key_file" env:"SERVICE_A_TLS_KEY_FILE"`
+	// ServiceBCAFiles are PEM files of CAs trusted for the certificate of
+	// service B, besides those of the system.
+	ServiceBCAFiles []string `yaml:"service_b_ca_files" env:"SERVICE_B_CA_FILES"`
+	// ClientCertFile and ClientKeyFile, when set, hold the key pair
+	// presented to service B.
+	ClientCertFile string `yaml:"client_cert_file" env:"SERVICE_A_CLIENT_CERT_FILE"`
+	ClientKeyFile  string `yaml:"client_key_file" env:"SERVICE_A_CLIENT_KEY_FILE"`
+}
+
+// ServiceB configures service B.
+type ServiceB struct {
+	Addr string `yaml:"addr" env:"SERVICE_B_ADDR"`
+	// Geocoding looks the weather up by the coordinates of the city, from
+	// the IBGE table at IBGEMunicipalitiesFile or the embedded one, rather
+	// than by its name.
+	Geocoding              bool   `yaml:"geocoding" env:"GEOCODING"`
+	IBGEMunicipalitiesFile string `yaml:"ibge_municipalities_file" env:"IBGE_MUNICIPALITIES_FILE"`
+	// CoalesceRequests shares a single upstream call between concurrent
+	// requests for the same CEP or location.
+	CoalesceRequests bool `yaml:"coalesce_requests" env:"COALESCE_REQUESTS"`
+	// TLSCertFile and TLSKeyFile, when set, serve HTTPS with the key pair
+	// in those files, read again when they change.
+	TLSCertFile string `yaml:"tls_cert_file" env:"SERVICE_B_TLS_CERT_FILE"`
+	TLSKeyFile  string `yaml:"tls_key_file" env:"SERVICE_B_TLS_KEY_FILE"`
+	// ClientCAFiles, when set, are PEM files of the only CAs whose
+	// certificates clients are accepted with, and AllowedClients, when set
+	// too, the only subjects accepted, as common names or distinguished
+	// names. The environment separates subjects by commas, so only common
+	// names fit there.
+	ClientCAFiles  []string `yaml:"client_ca_files" env:"SERVICE_B_CLIENT_CA_FILES"`
+	AllowedClients []string `yaml:"allowed_clients" env:"SERVICE_B_ALLOWED_CLIENTS"`
+}
+
+// Server configures the timeouts of the HTTP servers. The write timeout must
+// outlast [Request.Timeout], or slow answers are cut off instead of reported.
+type Server struct {
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+}
+
+// Telemetry configures where traces and metrics are sent.
+type Telemetry struct {
+	Endpoint string `yaml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+}
+
+// Request configures the time budget of a request.
+type Request struct {
+	Timeout time.Duration `yaml:"timeout" env:"REQUEST_TIMEOUT"`
+	// AddressBudgetShare is the share of the budget service B gives to the
+	// address lookup, leaving the rest to the weather lookup.
+	AddressBudgetShare float64 `yaml:"address_budget_share" env:"ADDRESS_BUDGET_SHARE"`
+}
+
+// Upstream configures the calls to the upstream providers.
+type Upstream struct {
+	// Timeout bounds every call, retries included, of the providers
+	// without a timeout of their own.
+	Timeout time.Duration `yaml:"timeout" env:"UPSTREAM_TIMEOUT"`
+	Retry   Retry         `yaml:"retry"`
+	Breaker Breaker       `yaml:"breaker"`
+	// CAFiles are PEM files of CAs trusted besides those of the system.
+	CAFiles []string `yaml:"ca_files" env:"UPSTREAM_CA_FILES"`
+	// Providers are the settings of each provider, by name.
+	Providers map[string]Provider `yaml:"providers"`
+}
+
+// Retry configures how failed upstream calls are retried. A single attempt
+// disables retries.
+type Retry struct {
+	MaxAttempts int           `yaml:"max_attempts" env:"RETRY_MAX_ATTEMPTS"`
+	BaseDelay   time.Duration `yaml:"base_delay" env:"RETRY_BASE_DELAY"`
+	MaxDelay    time.Duration `yaml:"max_delay" env:"RETRY_MAX_DELAY"`
+}
+
+// Breaker configures the circuit breaker of every remote provider.
+type Breaker struct {
+	Enabled      bool          `yaml:"enabled" env:"CIRCUIT_BREAKER"`
+	FailureRatio float64       `yaml:"failure_ratio" env:"BREAKER_FAILURE_RATIO"`
+	MinRequests  int           `yaml:"min_requests" env:"BREAKER_MIN_REQUESTS"`
+	Window       time.Duration `yaml:"window" env:"BREAKER_WINDOW"`
+	Cooldown     time.Duration `yaml:"cooldown" env:"BREAKER_COOLDOWN"`
+}
+
+// Provider configures the calls to an upstream provider.
+type Provider struct {
+	// Timeout replaces [Upstream.Timeout] when set.
+	Timeout time.Duration `yaml:"timeout"`
+	// RateLimit, when set, keeps the calls under the limits of the
+	// provider.
+	RateLimit *RateLimit `yaml:"rate_limit"`
+	Bulkhead  Bulkhead   `yaml:"bulkhead"`
+	// Pins, when set, are the public keys of which one must be in the
+	// certificate chain of the provider. See the tlstrust package.
+	Pins []string `yaml:"pins"`
+}
+
+// RateLimit configures the limits of a provider. See the ratelimit package.
+type RateLimit struct {
+	Rate       float64       `yaml:"rate"`
+	Burst      int           `yaml:"burst"`
+	Mode       string        `yaml:"mode"`
+	MaxWait    time.Duration `yaml:"max_wait"`
+	DailyQuota int           `yaml:"daily_quota"`
+}
+
+// Bulkhead configures the bulkhead of a provider. See the bulkhead package.
+type Bulkhead struct {
+	MaxConcurrent int           `yaml:"max_concurrent"`
+	QueueTimeout  time.Duration `yaml:"queue_timeout"`
+}
+
+// CEP configures the CEP providers.
+type CEP struct {
+	// Providers are asked, in order, until one of them answers.
+	Providers []string `yaml:"providers" env:"CEP_PROVIDERS"`
+	// NotFoundAuthoritative stops at the first provider that can not find
+	// a CEP instead of asking the next ones.
+	NotFoundAuthoritative bool `yaml:"not_found_authoritative" env:"CEP_NOT_FOUND_AUTHORITATIVE"`
+	// LocalDB is the index read by the "local" provider, built by the
+	// import-ceps command.
+	LocalDB string `yaml:"local_db" env:"LOCAL_CEP_DB"`
+}
+
+// Temperature configures the temperature providers.
+type Temperature struct {
+	// Providers are asked, in order, until one of them answers.
+	Providers         []string `yaml:"providers" env:"TEMPERATURE_PROVIDER"`
+	WeatherAPIKey     string   `yaml:"weatherapi_key" env:"WEATHERAPI_KEY" secret:"true"`
+	OpenWeatherMapKey string   `yaml:"openweathermap_key" env:"OPENWEATHERMAP_KEY" secret:"true"`
+}
+
+// Hedge configures the hedging of slow lookups, disabled unless Delay or
+// Percentile is set. With Percentile, Delay is used until MinSamples
+// latencies have been observed. The hedged calls go to CEPProvider and
+// TemperatureProvider or, when unset, to the same providers again.
+type Hedge struct {
+	Delay               time.Duration `yaml:"delay" env:"HEDGE_DELAY"`
+	Percentile          float64       `yaml:"percentile" env:"HEDGE_PERCENTILE"`
+	MinSamples          int           `yaml:"min_samples" env:"HEDGE_MIN_SAMPLES"`
+	CEPProvider         string        `yaml:"cep_provider" env:"HEDGE_CEP_PROVIDER"`
+	TemperatureProvider string        `yaml:"temperature_provider" env:"HEDGE_TEMPERATURE_PROVIDER"`
+}
+
+// Cache configures the caches of service B.
+type Cache struct {
+	// Backend is either memory or redis, shared by every replica.
+	Backend  string `yaml:"backend" env:"CACHE_BACKEND"`
+	RedisURL string `yaml:"redis_url" env:"REDIS_URL" secret:"url"`
+	// KeyVersion prefixes the keys in Redis, and can be bumped to
+	// invalidate every entry.
+	KeyVersion  string           `yaml:"key_version" env:"CACHE_KEY_VERSION"`
+	Address     AddressCache     `yaml:"address"`
+	Temperature TemperatureCache `yaml:"temperature"`
+}
+
+// AddressCache configures the address cache. A zero TTL disables it.
+type AddressCache struct {
+	TTL         time.Duration `yaml:"ttl" env:"ADDRESS_CACHE_TTL"`
+	Size        int           `yaml:"size" env:"ADDRESS_CACHE_SIZE"`
+	NotFoundTTL time.Duration `yaml:"not_found_ttl" env:"ADDRESS_NOT_FOUND_CACHE_TTL"`
+	// DiskPath, when set, keeps addresses in a file for DiskTTL, whose
+	// WarmStart most looked up entries are loaded into memory on start.
+	// Every DiskCompactInterval, expired entries are removed and the file is
+	// compacted.
+	DiskPath            string        `yaml:"disk_path" env:"ADDRESS_DISK_CACHE_PATH"`
+	DiskTTL             time.Duration `yaml:"disk_ttl" env:"ADDRESS_DISK_CACHE_TTL"`
+	DiskCompactInterval time.Duration `yaml:"disk_compact_interval" env:"ADDRESS_DISK_CACHE_COMPACT_INTERVAL"`
+	WarmStart           int           `yaml:"warm_start" env:"ADDRESS_CACHE_WARM_START"`
+}
+
+// TemperatureCache configures the temperature cache. A zero TTL disables it.
+// Past its TTL, a temperature is still served for StaleWhileRevalidate while
+// it is refreshed, and for StaleIfError when the refresh fails.
+type TemperatureCache struct {
+	TTL                  time.Duration `yaml:"ttl" env:"TEMPERATURE_CACHE_TTL"`
+	Size                 int           `yaml:"size" env:"TEMPERATURE_CACHE_SIZE"`
+	StaleWhileRevalidate time.Duration `yaml:"stale_while_revalidate" env:"TEMPERATURE_CACHE_STALE_WHILE_REVALIDATE"`
+	StaleIfError         time.Duration `yaml:"stale_if_error" env:"TEMPERATURE_CACHE_STALE_IF_ERROR"`
+}
+
+// Reload configures how the configuration is reloaded, which also happens on
+// SIGHUP. The addresses, the server, TLS, telemetry, geocoding, request
+// timeout and reload settings only take effect on restart.
+type Reload struct {
+	// WatchInterval, when set, is how often the config file is checked for
+	// changes, each one triggering a reload.
+	WatchInterval time.Duration `yaml:"watch_interval" env:"CONFIG_WATCH_INTERVAL"`
+}
+
+// Default returns the configuration used for everything left unset.
+func Default() *Config {
+	return &Config{
+		ServiceA: ServiceA{
+			Addr:            ":8000",
+			ServiceBURL:     "http://localhost:8080",
+			ClientRateLimit: true,
+		},
+		ServiceB: ServiceB{
+			Addr:             ":8080",
+			Geocoding:        true,
+			CoalesceRequests: true,
+		},
+		Server: Server{
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       time.Minute,
+		},
+		Telemetry: Telemetry{Endpoint: "http://localhost:4318"},
+		Request: Request{
+			Timeout:            10 * time.Second,
+			AddressBudgetShare: 0.4,
+		},
+		Upstream: Upstream{
+			Timeout: 5 * time.Second,
+			Retry: Retry{
+				MaxAttempts: retry.DefaultPolicy.MaxAttempts,
+				BaseDelay:   retry.DefaultPolicy.BaseDelay,
+				MaxDelay:    retry.DefaultPolicy.MaxDelay,
+			},
+			Breaker: Breaker{
+				Enabled:      true,
+				FailureRatio: breaker.DefaultSettings.FailureRatio,
+				MinRequests:  breaker.DefaultSettings.MinRequests,
+				Window:       breaker.DefaultSettings.Window,
+				Cooldown:     breaker.DefaultSettings.Cooldown,
+			},
+			Providers: map[string]Provider{},
+		},
+		CEP: CEP{
+			Providers:             []string{"viacep"},
+			NotFoundAuthoritative: true,
+			LocalDB:               "ceps.db",
+		},
+		Temperature: Temperature{Providers: []string{"wttr"}},
+		Hedge:       Hedge{MinSamples: 20},
+		Cache: Cache{
+			Backend:    "memory",
+			RedisURL:   "redis://localhost:6379/0",
+			KeyVersion: "v1",
+			Address: AddressCache{
+				TTL:                 24 * time.Hour,
+				Size:                10000,
+				NotFoundTTL:         10 * time.Minute,
+				DiskTTL:             30 * 24 * time.Hour,
+				DiskCompactInterval: time.Hour,
+				WarmStart:           1000,
+			},
+			Temperature: TemperatureCache{
+				TTL:                  5 * time.Minute,
+				Size:                 1000,
+				StaleWhileRevalidate: time.Minute,
+				StaleIfError:         time.Hour,
+			},
+		},
+	}
+}
+
+// Provider returns the effective settings of the provider name, its own
+// ones completed by the defaults.
+func (u Upstream) Provider(name string) Provider {
+	p := u.Providers[name]
+	if p.Timeout <= 0 {
+		p.Timeout = u.Timeout
+	}
+	if p.Bulkhead.MaxConcurrent <= 0 {
+		p.Bulkhead.MaxConcurrent = bulkhead.DefaultSettings.MaxConcurrent
+	}
+	if p.Bulkhead.QueueTimeout <= 0 {
+		p.Bulkhead.QueueTimeout = bulkhead.DefaultSettings.QueueTimeout
+	}
+	if p.RateLimit != nil {
+		rl := *p.RateLimit
+		if rl.Mode == "" {
+			rl.Mode = "wait"
+		}
+		if rl.MaxWait <= 0 {
+			rl.MaxWait = time.Second
+		}
+		p.RateLimit = &rl
+	}
+	return p
+}
@@ -0,0 +1,365 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable naming the config file, unless the
+// -config flag does.
+const FileEnv = "CONFIG_FILE"
+
+// Load returns the configuration of the command name, parsing args as its
+// flags and environ, in the form of [os.Environ], as its environment. It
+// also reports whether -print-config was given.
+//
+// When args ask for help, Load returns [flag.ErrHelp] after printing the
+// usage to the output of the flag set.
+func Load(name string, args, environ []string, output io.Writer) (*Config, bool, error) {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	file := fs.String("config", env[FileEnv], "YAML file to load the configuration from (env "+FileEnv+")")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration, secrets omitted, and exit")
+
+	cfg := Default()
+
+	flags := map[string]*leafFlag{}
+	walk(reflect.ValueOf(cfg).Elem(), "", func(key string, f reflect.StructField, v reflect.Value) {
+		var usage string
+		if e := f.Tag.Get("env"); e != "" {
+			usage = "env " + e
+		}
+		lf := &leafFlag{bool: v.Kind() == reflect.Bool, value: format(v)}
+		fs.Var(lf, flagName(key), usage)
+		flags[flagName(key)] = lf
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if fs.NArg() > 0 {
+		return nil, false, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *file != "" {
+		if err := cfg.loadFile(*file); err != nil {
+			return nil, false, err
+		}
+		cfg.File = *file
+	}
+
+	if err := cfg.loadEnv(env); err != nil {
+		return nil, false, err
+	}
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var errs []error
+	walk(reflect.ValueOf(cfg).Elem(), "", func(key string, _ reflect.StructField, v reflect.Value) {
+		if name := flagName(key); given[name] {
+			if err := set(v, flags[name].value); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", name, err))
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, false, err
+	}
+
+	cfg.normalize()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	// the defaults of the configured providers are filled in, so that they are
+	// printed as in effect
+	for name := range cfg.Upstream.Providers {
+		cfg.Upstream.Providers[name] = cfg.Upstream.Provider(name)
+	}
+
+	return cfg, *printConfig, nil
+}
+
+// normalize lowercases the provider names, so that they may be given in any
+// case.
+func (c *Config) normalize() {
+	for i, name := range c.CEP.Providers {
+		c.CEP.Providers[i] = strings.ToLower(name)
+	}
+	for i, name := range c.Temperature.Providers {
+		c.Temperature.Providers[i] = strings.ToLower(name)
+	}
+	c.Hedge.CEPProvider = strings.ToLower(strings.TrimSpace(c.Hedge.CEPProvider))
+	c.Hedge.TemperatureProvider = strings.ToLower(strings.TrimSpace(c.Hedge.TemperatureProvider))
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decoding config file %s: %w", path, err)
+	}
+	if c.Upstream.Providers == nil {
+		c.Upstream.Providers = map[string]Provider{}
+	}
+
+	return nil
+}
+
+func (c *Config) loadEnv(env map[string]string) error {
+	var errs []error
+
+	walk(reflect.ValueOf(c).Elem(), "", func(_ string, f reflect.StructField, v reflect.Value) {
+		key := f.Tag.Get("env")
+		if s := env[key]; key != "" && s != "" {
+			if err := set(v, s); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", key, err))
+			}
+		}
+	})
+
+	// the variables are sorted so that errors are reported in a stable order
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := env[k]
+		if v == "" {
+			continue
+		}
+
+		for _, pe := range providerEnv {
+			name, ok := strings.CutPrefix(k, pe.prefix)
+			if !ok {
+				continue
+			}
+
+			var err error
+			if name = strings.ToLower(name); slices.Contains(remoteProviders, name) {
+				p := c.Upstream.Providers[name]
+				err = pe.set(&p, v)
+				c.Upstream.Providers[name] = p
+			} else {
+				err = fmt.Errorf("unknown provider %q", name)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", k, err))
+			}
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// providerEnv are the environment variables setting the [Provider] named
+// after their prefix.
+var providerEnv = []struct {
+	prefix string
+	set    func(p *Provider, v string) error
+}{
+	{"UPSTREAM_TIMEOUT_", func(p *Provider, v string) (err error) {
+		p.Timeout, err = time.ParseDuration(v)
+		return err
+	}},
+	{"UPSTREAM_RATE_LIMIT_", func(p *Provider, v string) (err error) {
+		p.RateLimit, err = parseRateLimit(v)
+		return err
+	}},
+	{"UPSTREAM_BULKHEAD_", func(p *Provider, v string) (err error) {
+		p.Bulkhead, err = parseBulkhead(p.Bulkhead, v)
+		return err
+	}},
+	{"UPSTREAM_TLS_PINS_", func(p *Provider, v string) error {
+		p.Pins = splitList(v)
+		return nil
+	}},
+}
+
+// parseRateLimit parses limits given as comma separated key=value pairs, e.g.
+// "rate=5,burst=10,mode=reject,daily_quota=1000000". The keys are rate
+// (requests per second), burst, mode (wait or reject), max_wait and
+// daily_quota.
+func parseRateLimit(spec string) (*RateLimit, error) {
+	var rl RateLimit
+
+	for pair := range strings.SplitSeq(spec, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid pair %q", pair)
+		}
+
+		var err error
+		switch k {
+		case "rate":
+			rl.Rate, err = strconv.ParseFloat(v, 64)
+		case "burst":
+			rl.Burst, err = strconv.Atoi(v)
+		case "mode":
+			rl.Mode = v
+		case "max_wait":
+			rl.MaxWait, err = time.ParseDuration(v)
+		case "daily_quota":
+			rl.DailyQuota, err = strconv.Atoi(v)
+		default:
+			err = fmt.Errorf("unknown key %q", k)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", k, err)
+		}
+	}
+
+	return &rl, nil
+}
+
+// parseBulkhead parses bulkhead settings given as comma separated key=value
+// pairs, e.g. "max_concurrent=10,queue_timeout=200ms", over b.
+func parseBulkhead(b Bulkhead, spec string) (Bulkhead, error) {
+	for pair := range strings.SplitSeq(spec, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return Bulkhead{}, fmt.Errorf("invalid pair %q", pair)
+		}
+
+		var err error
+		switch k {
+		case "max_concurrent":
+			b.MaxConcurrent, err = strconv.Atoi(v)
+		case "queue_timeout":
+			b.QueueTimeout, err = time.ParseDuration(v)
+		default:
+			err = fmt.Errorf("unknown key %q", k)
+		}
+		if err != nil {
+			return Bulkhead{}, fmt.Errorf("parsing %s: %w", k, err)
+		}
+	}
+
+	return b, nil
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// walk calls fn with the dotted key of every setting of v, a struct, except
+// those in maps.
+func walk(v reflect.Value, prefix string, fn func(key string, f reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := prefix + name
+		fv := v.Field(i)
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			walk(fv, key+".", fn)
+		case reflect.Map:
+		default:
+			fn(key, f, fv)
+		}
+	}
+}
+
+// set parses s into v.
+func set(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		v.Set(reflect.ValueOf(splitList(s)))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// leafFlag holds the raw value of a flag, applied over the file and the
+// environment once they are loaded.
+type leafFlag struct {
+	bool  bool
+	value string
+}
+
+func (f *leafFlag) String() string { return f.value }
+
+func (f *leafFlag) Set(s string) error {
+	f.value = s
+	return nil
+}
+
+func (f *leafFlag) IsBoolFlag() bool { return f.bool }
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Print writes c to w as YAML, in the layout of the config file. The secrets
+// that are set are left out, so that the output can be saved as a config file
+// while they keep coming from the environment.
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(node(reflect.ValueOf(c).Elem())); err != nil {
+		return fmt.Errorf("encoding config: %w", err)
+	}
+
+	return enc.Close()
+}
+
+// node returns the YAML node of v.
+func node(v reflect.Value) *yaml.Node {
+	if v.Type() == durationType {
+		return scalar(time.Duration(v.Int()).String())
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		t := v.Type()
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" || hidden(v.Field(i), f.Tag.Get("secret")) {
+				continue
+			}
+			n.Content = append(n.Content, scalar(name), node(v.Field(i)))
+		}
+		return n
+	case reflect.Map:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, k := range keys {
+			n.Content = append(n.Content, scalar(k.String()), node(v.MapIndex(k)))
+		}
+		return n
+	case reflect.Pointer:
+		if v.IsNil() {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+		}
+		return node(v.Elem())
+	default:
+		n := &yaml.Node{}
+		_ = n.Encode(v.Interface())
+		return n
+	}
+}
+
+func scalar(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// hidden reports whether v, of a field whose secret tag is secret, holds a
+// secret. URLs only hold one when they have a password.
+func hidden(v reflect.Value, secret string) bool {
+	if secret == "" || v.Kind() != reflect.String || v.String() == "" {
+		return false
+	}
+	if secret == "url" {
+		if u, err := url.Parse(v.String()); err == nil {
+			_, ok := u.User.Password()
+			return ok
+		}
+	}
+	return true
+}
+
+// format returns v as it would be given in a flag.
+func format(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/vinicius-lino-figueiredo/pos-go-expert-desafio-4/internal/adapter/tlstrust"
+)
+
+var (
+	cepProviders         = []string{"viacep", "brasilapi", "opencep", "postmon", "local"}
+	temperatureProviders = []string{"wttr", "openmeteo", "weatherapi", "openweathermap"}
+	// remoteProviders are those called over HTTP, set by [Upstream.Providers].
+	remoteProviders = []string{"viacep", "brasilapi", "opencep", "postmon", "wttr", "openmeteo", "weatherapi", "openweathermap"}
+)
+
+// Validate reports every invalid setting of c, each by its key in the config
+// file.
+func (c *Config) Validate() error {
+	v := validator{}
+
+	v.check(c.ServiceA.Addr != "", "service_a.addr", "must be set")
+	v.url("service_a.service_b_url", c.ServiceA.ServiceBURL)
+	v.check(c.ServiceB.Addr != "", "service_b.addr", "must be set")
+	v.keyPair("service_a.tls", c.ServiceA.TLSCertFile, c.ServiceA.TLSKeyFile)
+	v.keyPair("service_a.client", c.ServiceA.ClientCertFile, c.ServiceA.ClientKeyFile)
+	if c.ServiceA.ClientCertFile != "" || len(c.ServiceA.ServiceBCAFiles) > 0 {
+		u, err := url.Parse(c.ServiceA.ServiceBURL)
+		v.check(err == nil && u.Scheme == "https", "service_a.service_b_url",
+			"must be https to verify service B or present a client certificate")
+	}
+	v.keyPair("service_b.tls", c.ServiceB.TLSCertFile, c.ServiceB.TLSKeyFile)
+	v.check(len(c.ServiceB.ClientCAFiles) == 0 || c.ServiceB.TLSCertFile != "",
+		"service_b.client_ca_files", "requires service_b.tls_cert_file")
+	v.check(len(c.ServiceB.AllowedClients) == 0 || len(c.ServiceB.ClientCAFiles) > 0,
+		"service_b.allowed_clients", "requires service_b.client_ca_files")
+
+	v.nonNegative("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	v.nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	v.nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	v.nonNegative("server.idle_timeout", c.Server.IdleTimeout)
+	v.check(c.Server.WriteTimeout == 0 || c.Server.WriteTimeout > c.Request.Timeout,
+		"server.write_timeout", "must exceed request.timeout (%s)", c.Request.Timeout)
+
+	v.url("telemetry.endpoint", c.Telemetry.Endpoint)
+
+	v.check(c.Request.Timeout > 0, "request.timeout", "must be positive")
+	v.check(c.Request.AddressBudgetShare > 0 && c.Request.AddressBudgetShare <= 1,
+		"request.address_budget_share", "must be in (0, 1]")
+
+	v.check(c.Upstream.Timeout > 0, "upstream.timeout", "must be positive")
+	v.check(c.Upstream.Retry.MaxAttempts >= 1, "upstream.retry.max_attempts", "must be at least 1")
+	v.nonNegative("upstream.retry.base_delay", c.Upstream.Retry.BaseDelay)
+	v.check(c.Upstream.Retry.MaxDelay >= c.Upstream.Retry.BaseDelay,
+		"upstream.retry.max_delay", "must not be below upstream.retry.base_delay")
+	v.check(c.Upstream.Breaker.FailureRatio > 0 && c.Upstream.Breaker.FailureRatio <= 1,
+		"upstream.breaker.failure_ratio", "must be in (0, 1]")
+	v.check(c.Upstream.Breaker.MinRequests >= 1, "upstream.breaker.min_requests", "must be at least 1")
+	v.check(c.Upstream.Breaker.Window > 0, "upstream.breaker.window", "must be positive")
+	v.check(c.Upstream.Breaker.Cooldown > 0, "upstream.breaker.cooldown", "must be positive")
+
+	names := make([]string, 0, len(c.Upstream.Providers))
+	for name := range c.Upstream.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p, key := c.Upstream.Providers[name], "upstream.providers."+name
+		v.nonNegative(key+".timeout", p.Timeout)
+		v.check(p.Bulkhead.MaxConcurrent >= 0, key+".bulkhead.max_concurrent", "must not be negative")
+		v.nonNegative(key+".bulkhead.queue_timeout", p.Bulkhead.QueueTimeout)
+		if rl := p.RateLimit; rl != nil {
+			v.check(rl.Rate >= 0, key+".rate_limit.rate", "must not be negative")
+			v.check(rl.Burst >= 0, key+".rate_limit.burst", "must not be negative")
+			v.check(slices.Contains([]string{"", "wait", "reject"}, rl.Mode),
+				key+".rate_limit.mode", "must be wait or reject, not %q", rl.Mode)
+			v.nonNegative(key+".rate_limit.max_wait", rl.MaxWait)
+			v.check(rl.DailyQuota >= 0, key+".rate_limit.daily_quota", "must not be negative")
+		}
+		for _, pin := range p.Pins {
+			_, err := tlstrust.ParsePin(pin)
+			v.check(err == nil, key+".pins", "%v", err)
+		}
+	}
+
+	v.providers("cep.providers", c.CEP.Providers, cepProviders)
+	v.check(!slices.Contains(c.CEP.Providers, "local") || c.CEP.LocalDB != "",
+		"cep.local_db", "must be set to use the local provider")
+
+	v.providers("temperature.providers", c.Temperature.Providers, temperatureProviders)
+	uses := func(name string) bool {
+		return slices.Contains(c.Temperature.Providers, name) || c.Hedge.TemperatureProvider == name
+	}
+	v.check(!uses("weatherapi") || c.Temperature.WeatherAPIKey != "",
+		"temperature.weatherapi_key", "must be set to use the weatherapi provider")
+	v.check(!uses("openweathermap") || c.Temperature.OpenWeatherMapKey != "",
+		"temperature.openweathermap_key", "must be set to use the openweathermap provider")
+
+	v.nonNegative("hedge.delay", c.Hedge.Delay)
+	v.check(c.Hedge.Percentile >= 0 && c.Hedge.Percentile < 1, "hedge.percentile", "must be in [0, 1)")
+	v.check(c.Hedge.MinSamples >= 0, "hedge.min_samples", "must not be negative")
+	if c.Hedge.CEPProvider != "" {
+		v.providers("hedge.cep_provider", []string{c.Hedge.CEPProvider}, cepProviders)
+	}
+	if c.Hedge.TemperatureProvider != "" {
+		v.providers("hedge.temperature_provider", []string{c.Hedge.TemperatureProvider}, temperatureProviders)
+	}
+
+	v.check(c.Cache.Backend == "memory" || c.Cache.Backend == "redis",
+		"cache.backend", "must be memory or redis, not %q", c.Cache.Backend)
+	if c.Cache.Backend == "redis" {
+		v.url("cache.redis_url", c.Cache.RedisURL)
+		v.check(c.Cache.KeyVersion != "", "cache.key_version", "must be set")
+	}
+	v.nonNegative("cache.address.ttl", c.Cache.Address.TTL)
+	v.check(c.Cache.Address.Size > 0, "cache.address.size", "must be positive")
+	v.nonNegative("cache.address.not_found_ttl", c.Cache.Address.NotFoundTTL)
+	v.nonNegative("cache.address.disk_ttl", c.Cache.Address.DiskTTL)
+	v.nonNegative("cache.address.disk_compact_interval", c.Cache.Address.DiskCompactInterval)
+	v.check(c.Cache.Address.WarmStart >= 0, "cache.address.warm_start", "must not be negative")
+	v.nonNegative("cache.temperature.ttl", c.Cache.Temperature.TTL)
+	v.check(c.Cache.Temperature.Size > 0, "cache.temperature.size", "must be positive")
+	v.nonNegative("cache.temperature.stale_while_revalidate", c.Cache.Temperature.StaleWhileRevalidate)
+	v.nonNegative("cache.temperature.stale_if_error", c.Cache.Temperature.StaleIfError)
+
+	v.nonNegative("reload.watch_interval", c.Reload.WatchInterval)
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, key, format string, args ...any) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+}
+
+func (v *validator) nonNegative(key string, d time.Duration) {
+	v.check(d >= 0, key, "must not be negative")
+}
+
+func (v *validator) url(key, s string) {
+	u, err := url.Parse(s)
+	v.check(err == nil && u.Scheme != "" && u.Host != "", key, "must be an absolute URL, not %q", s)
+}
+
+// keyPair checks that the cert and key files of prefix are set together.
+func (v *validator) keyPair(prefix, certFile, keyFile string) {
+	v.check((certFile == "") == (keyFile == ""), prefix+"_key_file",
+		"must be set along with %s_cert_file", prefix)
+}
+
+func (v *validator) providers(key string, names, known []string) {
+	v.check(len(names) > 0, key, "must name at least one provider")
+	for _, name := range names {
+		v.check(slices.Contains(known, name), key, "unknown provider %q, expected one of %v", name, known)
+	}
+}